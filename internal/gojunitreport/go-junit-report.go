@@ -18,6 +18,14 @@ type parser interface {
 	Events() []gotest.Event
 }
 
+// deterministicHostname and deterministicTimestamp are the hostname and time
+// used in reports when Config.Deterministic is set. They are documented in
+// Config.Deterministic and the -deterministic flag, which must be kept up to
+// date when they change.
+const deterministicHostname = "hostname"
+
+var deterministicTimestamp = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // Config contains the go-junit-report command configuration.
 type Config struct {
 	Parser        string
@@ -28,12 +36,24 @@ type Config struct {
 	Properties    map[string]string
 	TimestampFunc func() time.Time
 
+	// Deterministic replaces all values in the report that depend on the
+	// environment or the current time: Hostname is replaced by "hostname"
+	// and TimestampFunc by a clock that always returns 2022-01-01T00:00:00Z.
+	// The same input and configuration then always result in the same
+	// report, e.g. for golden file tests.
+	Deterministic bool
+
 	// For debugging
 	PrintEvents bool
 }
 
 // Run runs the go-junit-report command and returns the generated report.
 func (c Config) Run(input io.Reader, output io.Writer) (*gtr.Report, error) {
+	if c.Deterministic {
+		c.Hostname = deterministicHostname
+		c.TimestampFunc = func() time.Time { return deterministicTimestamp }
+	}
+
 	var p parser
 	switch c.Parser {
 	case "gotest":
@@ -68,11 +68,8 @@ func testRun(inputFile, reportFile string, config Config, t *testing.T) {
 	if strings.HasSuffix(inputFile, ".gojson.txt") {
 		config.Parser = "gojson"
 	}
-	config.Hostname = "hostname"
+	config.Deterministic = true
 	config.Properties = map[string]string{"go.version": "1.0"}
-	config.TimestampFunc = func() time.Time {
-		return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
-	}
 
 	var output bytes.Buffer
 	if _, err := config.Run(input, &output); err != nil {
@@ -84,6 +81,42 @@ func testRun(inputFile, reportFile string, config Config, t *testing.T) {
 	}
 }
 
+func TestRunDeterministic(t *testing.T) {
+	input, err := os.ReadFile(testDataDir + "001-pass-fail-skip.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	environments := []Config{
+		{Hostname: "ci-runner-1", TimestampFunc: func() time.Time { return time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC) }},
+		{Hostname: "laptop.local", TimestampFunc: time.Now},
+	}
+	var outputs []string
+	for _, config := range environments {
+		config.Parser = "gotest"
+		config.Deterministic = true
+		var output bytes.Buffer
+		if _, err := config.Run(bytes.NewReader(input), &output); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, output.String())
+	}
+
+	if diff := cmp.Diff(outputs[0], outputs[1]); diff != "" {
+		t.Errorf("Deterministic output differs between environments, diff (-first, +second):\n%v", diff)
+	}
+	for _, unwanted := range []string{"ci-runner-1", "laptop.local"} {
+		if strings.Contains(outputs[0], unwanted) {
+			t.Errorf("Deterministic output contains hostname %q:\n%s", unwanted, outputs[0])
+		}
+	}
+	for _, wanted := range []string{`hostname="hostname"`, `timestamp="2022-01-01T00:00:00Z"`} {
+		if !strings.Contains(outputs[0], wanted) {
+			t.Errorf("Deterministic output does not contain %s:\n%s", wanted, outputs[0])
+		}
+	}
+}
+
 func testFileConfig(filename string) (config Config, reportFile string, err error) {
 	var prefix string
 	if idx := strings.IndexByte(filename, '-'); idx < 0 {
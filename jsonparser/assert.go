@@ -0,0 +1,20 @@
+package jsonparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// AssertNoFailures reports an error to tb for every failed test in this
+// report, including the output of that test. This can be used to replay a
+// previously captured go test run in a test or benchmark.
+func (r *Report) AssertNoFailures(tb testing.TB) {
+	tb.Helper()
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			if t.Result == FAIL {
+				tb.Errorf("%s: %s failed:\n%s", pkg.Name, t.Name, strings.Join(t.Output, ""))
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package jsonparser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeTB records the errors reported to it instead of failing the test.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertNoFailures(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Tests: []*Test{
+			{Name: "TestPass", Result: PASS, Output: []string{"ok\n"}},
+			{Name: "TestFail", Result: FAIL, Output: []string{"    a_test.go:6: broken\n"}},
+		}},
+		{Name: "package/b", Tests: []*Test{
+			{Name: "TestSkip", Result: SKIP},
+			{Name: "TestAlsoFail", Result: FAIL},
+		}},
+	}}
+
+	tb := &fakeTB{}
+	report.AssertNoFailures(tb)
+
+	want := []string{
+		"package/a: TestFail failed:\n    a_test.go:6: broken\n",
+		"package/b: TestAlsoFail failed:\n",
+	}
+	if diff := cmp.Diff(want, tb.errors); diff != "" {
+		t.Errorf("unexpected errors reported, diff (-want, +got):\n%s\n", diff)
+	}
+}
@@ -0,0 +1,74 @@
+package jsonparser
+
+import "time"
+
+// BenchmarkDelta describes the change in time per operation of a single
+// benchmark between two reports.
+type BenchmarkDelta struct {
+	Name    string
+	Base    time.Duration // time per operation in the base report
+	Current time.Duration // time per operation in the current report
+
+	// DeltaPct is the change from Base to Current as a percentage of Base.
+	// A positive value means the benchmark got slower.
+	DeltaPct float64
+
+	// Regression is set if DeltaPct exceeds the threshold given to
+	// CompareBenchmarks.
+	Regression bool
+}
+
+// CompareBenchmarks compares the benchmarks in report cur with those in report
+// base and returns the change for every benchmark present in both. Benchmarks
+// are matched by name regardless of the package they are in, and benchmarks
+// that occur multiple times in a report (e.g. when run with -count) are
+// averaged. A benchmark is marked as a regression if its time per operation
+// increased by more than thresholdPct percent. Deltas are returned in the
+// order the benchmarks appear in cur.
+func CompareBenchmarks(base, cur *Report, thresholdPct float64) []BenchmarkDelta {
+	baseDurations, _ := averageBenchmarkDurations(base)
+	curDurations, names := averageBenchmarkDurations(cur)
+
+	var deltas []BenchmarkDelta
+	for _, name := range names {
+		b, ok := baseDurations[name]
+		if !ok || b == 0 {
+			continue
+		}
+		c := curDurations[name]
+		delta := BenchmarkDelta{
+			Name:     name,
+			Base:     b,
+			Current:  c,
+			DeltaPct: float64(c-b) / float64(b) * 100,
+		}
+		delta.Regression = delta.DeltaPct > thresholdPct
+		deltas = append(deltas, delta)
+	}
+	return deltas
+}
+
+// averageBenchmarkDurations returns the average time per operation of every
+// benchmark in report r by name, and the benchmark names in the order they
+// first appear. Benchmarks that did not pass are ignored.
+func averageBenchmarkDurations(r *Report) (map[string]time.Duration, []string) {
+	var names []string
+	total := make(map[string]time.Duration)
+	count := make(map[string]int)
+	for _, pkg := range r.Packages {
+		for _, b := range pkg.Benchmarks {
+			if b.Result != PASS {
+				continue
+			}
+			if _, ok := count[b.Name]; !ok {
+				names = append(names, b.Name)
+			}
+			total[b.Name] += b.Duration
+			count[b.Name]++
+		}
+	}
+	for name, n := range count {
+		total[name] /= time.Duration(n)
+	}
+	return total, names
+}
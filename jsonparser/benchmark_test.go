@@ -0,0 +1,36 @@
+package jsonparser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareBenchmarks(t *testing.T) {
+	base := &Report{Packages: []*Package{
+		{Name: "package/a", Benchmarks: []*Benchmark{
+			{Name: "BenchmarkImproved", Duration: 200 * time.Nanosecond},
+			{Name: "BenchmarkRegressed", Duration: 100 * time.Nanosecond},
+			{Name: "BenchmarkRemoved", Duration: 100 * time.Nanosecond},
+		}},
+	}}
+	cur := &Report{Packages: []*Package{
+		{Name: "package/a", Benchmarks: []*Benchmark{
+			{Name: "BenchmarkImproved", Duration: 150 * time.Nanosecond},
+			{Name: "BenchmarkNew", Duration: 100 * time.Nanosecond},
+		}},
+		{Name: "package/moved", Benchmarks: []*Benchmark{
+			{Name: "BenchmarkRegressed", Duration: 120 * time.Nanosecond},
+			{Name: "BenchmarkRegressed", Duration: 140 * time.Nanosecond},
+		}},
+	}}
+
+	want := []BenchmarkDelta{
+		{Name: "BenchmarkImproved", Base: 200 * time.Nanosecond, Current: 150 * time.Nanosecond, DeltaPct: -25},
+		{Name: "BenchmarkRegressed", Base: 100 * time.Nanosecond, Current: 130 * time.Nanosecond, DeltaPct: 30, Regression: true},
+	}
+	if diff := cmp.Diff(want, CompareBenchmarks(base, cur, 10)); diff != "" {
+		t.Errorf("CompareBenchmarks incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
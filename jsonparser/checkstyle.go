@@ -0,0 +1,106 @@
+package jsonparser
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// regexLocation matches test output that was logged with a file and line
+// location, e.g. "    main_test.go:12: got 1, want 2".
+var regexLocation = regexp.MustCompile(`^\s*([^\s:]+\.go):(\d+): (.*)$`)
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// location is a file and line reference found in the output of a test.
+type location struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// failureLocations returns all file and line references in the output of
+// test t. File names without a directory are assumed to be relative to the
+// directory of package pkg.
+func failureLocations(pkg string, t *Test) []location {
+	var locations []location
+	for _, line := range t.Output {
+		matches := regexLocation.FindStringSubmatch(strings.TrimRight(line, "\n"))
+		if matches == nil {
+			continue
+		}
+		n, err := strconv.Atoi(matches[2])
+		if err != nil {
+			continue
+		}
+		file := matches[1]
+		if !strings.Contains(file, "/") {
+			file = path.Join(pkg, file)
+		}
+		locations = append(locations, location{File: file, Line: n, Message: matches[3]})
+	}
+	return locations
+}
+
+// CheckstyleReport writes the failed tests in report to w as checkstyle XML.
+// Every line of output of a failed test that references a file and line
+// becomes an error in that file. Failed tests without any locations in their
+// output are not included.
+func CheckstyleReport(report *Report, w io.Writer) error {
+	cs := checkstyleReport{Version: "4.3"}
+	files := make(map[string]int) // index of each file in cs.Files
+	for _, pkg := range report.Packages {
+		for _, t := range pkg.Tests {
+			if t.Result != FAIL {
+				continue
+			}
+			for _, loc := range failureLocations(pkg.Name, t) {
+				idx, ok := files[loc.File]
+				if !ok {
+					idx = len(cs.Files)
+					files[loc.File] = idx
+					cs.Files = append(cs.Files, checkstyleFile{Name: loc.File})
+				}
+				cs.Files[idx].Errors = append(cs.Files[idx].Errors, checkstyleError{
+					Line:     loc.Line,
+					Severity: "error",
+					Message:  loc.Message,
+					Source:   t.Name,
+				})
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(cs); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
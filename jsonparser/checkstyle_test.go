@@ -0,0 +1,45 @@
+package jsonparser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckstyleReport(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestPass", Result: PASS, Output: []string{"    main_test.go:5: all good\n"}},
+			{Name: "TestFail", Result: FAIL, Output: []string{
+				"=== RUN   TestFail\n",
+				"    main_test.go:12: got 1, want 2\n",
+				"    main_test.go:13: got <nil>\n",
+				"--- FAIL: TestFail (0.00s)\n",
+			}},
+			{Name: "TestNoLocation", Result: FAIL, Output: []string{"panic: boom\n"}},
+			{Name: "TestOtherFile", Result: FAIL, Output: []string{"    internal/helper.go:7: helper failed\n"}},
+		},
+	}}}
+
+	var buf bytes.Buffer
+	if err := CheckstyleReport(report, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+	<file name="package/name/main_test.go">
+		<error line="12" severity="error" message="got 1, want 2" source="TestFail"></error>
+		<error line="13" severity="error" message="got &lt;nil&gt;" source="TestFail"></error>
+	</file>
+	<file name="internal/helper.go">
+		<error line="7" severity="error" message="helper failed" source="TestOtherFile"></error>
+	</file>
+</checkstyle>
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("CheckstyleReport output incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
@@ -0,0 +1,130 @@
+package jsonparser
+
+import (
+	"io"
+	"strings"
+)
+
+// echoWriter copies the output of events to the echo writer of a Parser. In
+// line mode, see EchoLines, output is only written once a complete line is
+// available. Incomplete lines are buffered separately for every test, so the
+// output of parallel tests is never mixed within a single line. In failures
+// mode, see EchoFailuresOnly, the output of every test is buffered until it
+// completes, and only written if it failed.
+type echoWriter struct {
+	w        io.Writer
+	lines    bool
+	failures bool
+
+	partial map[string]string // incomplete line by test
+	order   []string          // tests with an incomplete line, in order
+
+	buffered      map[string][]string // output of running tests, failures mode
+	bufferedOrder []string
+}
+
+func newEchoWriter(w io.Writer, lines, failures bool) *echoWriter {
+	return &echoWriter{
+		w:        w,
+		lines:    lines,
+		failures: failures,
+		partial:  make(map[string]string),
+		buffered: make(map[string][]string),
+	}
+}
+
+// write echoes the output of lineoutput. Errors are ignored, echoing output is
+// best-effort.
+func (e *echoWriter) write(lineoutput LineOutput) {
+	if e.w == nil {
+		return
+	}
+	key := lineoutput.Package + "\x00" + lineoutput.Test
+	if !e.failures || lineoutput.Test == "" {
+		e.emit(key, lineoutput.Output)
+		return
+	}
+
+	switch lineoutput.Action {
+	case "output":
+		if _, ok := e.buffered[key]; !ok {
+			e.bufferedOrder = append(e.bufferedOrder, key)
+		}
+		e.buffered[key] = append(e.buffered[key], lineoutput.Output)
+	case "pass", "fail", "skip", "bench":
+		output := e.buffered[key]
+		if _, ok := e.buffered[key]; ok {
+			delete(e.buffered, key)
+			e.bufferedOrder = removeKey(e.bufferedOrder, key)
+		}
+		if parseResult(lineoutput.Action) == FAIL {
+			e.emit(key, strings.Join(output, ""))
+		}
+	}
+}
+
+// emit writes output of the test with the given key to the echo writer.
+func (e *echoWriter) emit(key, output string) {
+	if output == "" {
+		return
+	}
+	if !e.lines {
+		io.WriteString(e.w, output)
+		return
+	}
+
+	prev, ok := e.partial[key]
+	output = prev + output
+	i := strings.LastIndex(output, "\n")
+	if i < 0 {
+		if !ok {
+			e.order = append(e.order, key)
+		}
+		e.partial[key] = output
+		return
+	}
+
+	io.WriteString(e.w, output[:i+1])
+	if rest := output[i+1:]; rest != "" {
+		if !ok {
+			e.order = append(e.order, key)
+		}
+		e.partial[key] = rest
+	} else if ok {
+		e.remove(key)
+	}
+}
+
+// flush writes all output that is still buffered. In failures mode this
+// includes the output of tests that never completed.
+func (e *echoWriter) flush() {
+	if e.w == nil {
+		return
+	}
+	for _, key := range e.bufferedOrder {
+		e.emit(key, strings.Join(e.buffered[key], ""))
+	}
+	e.buffered = make(map[string][]string)
+	e.bufferedOrder = nil
+
+	for _, key := range e.order {
+		io.WriteString(e.w, e.partial[key])
+	}
+	e.order = nil
+	e.partial = make(map[string]string)
+}
+
+func (e *echoWriter) remove(key string) {
+	delete(e.partial, key)
+	e.order = removeKey(e.order, key)
+}
+
+// removeKey returns keys without the first occurrence of key.
+func removeKey(keys []string, key string) []string {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,22 @@
+package jsonparser
+
+import "strings"
+
+// multiError combines multiple errors into a single error.
+type multiError []error
+
+func (m multiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// errorOrNil returns m as an error, or nil if m does not contain any errors.
+func (m multiError) errorOrNil() error {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
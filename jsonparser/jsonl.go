@@ -0,0 +1,44 @@
+package jsonparser
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// packageSummary is the JSON Lines representation of a single package.
+type packageSummary struct {
+	Name       string  `json:"name"`
+	Tests      int     `json:"tests"`
+	Passed     int     `json:"passed"`
+	Failed     int     `json:"failed"`
+	Skipped    int     `json:"skipped"`
+	Errored    int     `json:"errored"`
+	Benchmarks int     `json:"benchmarks"`
+	Duration   float64 `json:"duration_seconds"`
+	Coverage   string  `json:"coverage_percentage,omitempty"`
+}
+
+// WritePackageJSONL writes a summary of every package in report to w, as a
+// single line of compact JSON per package. Each summary contains the package
+// name, the test counts, the duration in seconds and the coverage percentage.
+func WritePackageJSONL(report *Report, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, pkg := range report.Packages {
+		s := pkg.Stats()
+		summary := packageSummary{
+			Name:       pkg.Name,
+			Tests:      s.Total,
+			Passed:     s.Passed,
+			Failed:     s.Failed,
+			Skipped:    s.Skipped,
+			Errored:    s.Errored,
+			Benchmarks: s.Benchmarks,
+			Duration:   s.Duration.Seconds(),
+			Coverage:   pkg.CoveragePct,
+		}
+		if err := enc.Encode(summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
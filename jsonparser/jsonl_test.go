@@ -0,0 +1,39 @@
+package jsonparser
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWritePackageJSONL(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{
+			Name:        "package/a",
+			Duration:    1500 * time.Millisecond,
+			CoveragePct: "75.0",
+			Tests: []*Test{
+				{Name: "TestPass", Result: PASS},
+				{Name: "TestFail", Result: FAIL},
+			},
+		},
+		{
+			Name:  "package/b",
+			Tests: []*Test{{Name: "TestSkip", Result: SKIP}},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := WritePackageJSONL(report, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name":"package/a","tests":2,"passed":1,"failed":1,"skipped":0,"errored":0,"benchmarks":0,"duration_seconds":1.5,"coverage_percentage":"75.0"}
+{"name":"package/b","tests":1,"passed":0,"failed":0,"skipped":1,"errored":0,"benchmarks":0,"duration_seconds":0}
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("WritePackageJSONL output incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
@@ -0,0 +1,940 @@
+package jsonparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// JUnitWriter writes a Report as JUnit XML. Testsuites and testcases are
+// written in the order they appear in the Report, which for a parsed Report is
+// the order in which packages and tests completed. Call Report.Sort before
+// writing to get a name-based ordering instead. Package errors, see
+// PackageError, are written as errored testcases named "setup" or "teardown"
+// after the testcases of their package.
+//
+// Test and package names are escaped in attribute values. Characters that
+// cannot be represented in XML at all, such as most control characters, are
+// replaced by the Unicode replacement character U+FFFD.
+//
+// JUnitWriter uses its own minimal XML schema rather than the types in package
+// junit. Those types always write the id of testsuites and write all output as
+// CDATA, and cannot represent nested testsuites or the assertions and retries
+// of testcases, all of which depend on the configuration of JUnitWriter.
+// Timestamps are written in the same format by default, see
+// DefaultTimestampLayout.
+type JUnitWriter struct {
+	SkipXMLHeader bool
+
+	// Surefire enables compatibility with the layout written by the Maven
+	// Surefire plugin. Slashes in package names are replaced by dots to form
+	// Java-style classnames, and WritePerPackage writes files named
+	// TEST-<classname>.xml containing a single root testsuite element.
+	Surefire bool
+
+	// IncludePassingOutput enables writing the output of passing tests to
+	// the system-out element of their testcase. By default only the output
+	// of failed and skipped tests is written.
+	IncludePassingOutput bool
+
+	// TimestampLayout is the time.Format layout used for the timestamp
+	// attribute of testsuites. It defaults to DefaultTimestampLayout. An
+	// invalid layout causes Write to return an error.
+	TimestampLayout string
+
+	// SubtestSeparator, if set, replaces the "/" separator in the names of
+	// subtests, e.g. "." or " › ". The original name of the test is kept in
+	// the test.name property of each testcase that was renamed.
+	SubtestSeparator string
+
+	// StripSummaryLines removes the PASS and FAIL status lines and the
+	// "ok  pkg  1.234s" style package summary lines from the output of
+	// tests before it is written. By default all output is kept.
+	StripSummaryLines bool
+
+	// ModulePath, if set, is removed from the start of package names when
+	// they are used as testsuite names and classnames. For example, with
+	// ModulePath "github.com/org/repo" the package
+	// "github.com/org/repo/service/foo" is written as "service/foo". Packages
+	// outside of the module, and the module root package itself, keep their
+	// full name.
+	ModulePath string
+
+	// NonZeroDurations writes the time of testcases that would otherwise be
+	// written as zero as the smallest nonzero time that can be written
+	// instead, i.e. DurationRounding if it is set and "0.001" otherwise. Some
+	// JUnit consumers ignore or reject testcases with a time of zero. By
+	// default durations are written as is.
+	NonZeroDurations bool
+
+	// SuiteIDs enables the id attribute on testsuites. Ids start at zero and
+	// are incremented for every testsuite in the order they are written.
+	SuiteIDs bool
+
+	// NestSubtests writes every top-level test that has subtests as a
+	// testsuite nested in the testsuite of its package, containing the
+	// subtests as testcases. Only one level of testsuites is added: subtests
+	// of subtests are written to the testsuite of their top-level test, so
+	// the depth of the report does not depend on how deeply subtests are
+	// nested. The top-level test itself is only written as a
+	// testcase of the nested testsuite if it failed while none of its
+	// subtests did, so that the failure is not lost. The counts of nested
+	// testsuites are included in those of their parent testsuite.
+	NestSubtests bool
+
+	// MaxFailureBytes, if positive, limits the size of the body of failure
+	// elements. Longer bodies keep their first and last MaxFailureBytes/2
+	// bytes, and the part in between is replaced by a marker mentioning the
+	// number of bytes that were left out. The marker is not included in the
+	// limit. By default failure bodies are not limited.
+	MaxFailureBytes int
+
+	// Command, if set, is the go test command line that produced the report.
+	// It is written as the go.command property of every testsuite.
+	Command string
+
+	// PackageProperty adds the full name of the package of every testcase
+	// as its package property. This can be used to keep the full import path
+	// available when classnames are shortened using ModulePath or Surefire.
+	PackageProperty bool
+
+	// TestIDProperty adds the stable identifier of every test, see Test.ID,
+	// as the id property of its testcase.
+	TestIDProperty bool
+
+	// LogProperties adds the key=value pairs logged by tests as properties
+	// of their testcase, e.g. a line "    main_test.go:12: user=alice id=42"
+	// in the output of a test adds the properties user and id. Only lines
+	// that consist entirely of key=value pairs, optionally preceded by the
+	// file and line location added by t.Log, are used. Values may be quoted
+	// to include spaces. The output of the test is not changed.
+	LogProperties bool
+
+	// CDATA writes the output of tests in failure, error, skipped,
+	// system-out and system-err elements as CDATA sections rather than
+	// escaping it, which keeps output containing many special characters
+	// readable. Output that contains "]]>" is split over multiple CDATA
+	// sections.
+	CDATA bool
+
+	// GitLab writes a report that is compatible with the JUnit report parser
+	// of GitLab CI, which only supports a subset of the JUnit XML format. It
+	// makes the following changes to the report:
+	//
+	//   - The id and timestamp attributes of testsuites are left out, even
+	//     if SuiteIDs is enabled.
+	//   - Testsuite and testcase properties are left out, including the
+	//     coverage and test.name properties.
+	//   - The output of skipped tests is written to the system-out element
+	//     of their testcase rather than to the skipped element, since GitLab
+	//     only displays the system-out of skipped tests.
+	//
+	// GitLab does not support nested testsuites, so it cannot be combined
+	// with NestSubtests.
+	GitLab bool
+
+	// DurationRounding, if positive, rounds the durations written in time
+	// attributes to a multiple of DurationRounding, e.g. time.Millisecond.
+	// Rounding only affects the report that is written; the durations in
+	// the Report are not changed. Times are written with millisecond
+	// precision, or with as many decimals as needed to represent
+	// DurationRounding if it is smaller than a millisecond. By default
+	// durations are written with millisecond precision without rounding.
+	DurationRounding time.Duration
+
+	// SeparateBenchmarks writes the benchmarks of every package to their
+	// own testsuite, named after the package with a " [bench]" suffix, which
+	// directly follows the testsuite of the package. The time of this
+	// testsuite is the sum of the durations of its benchmarks. Files written
+	// by WritePerPackage with Surefire enabled contain a single testsuite,
+	// so they keep benchmarks in the testsuite of their package. By default
+	// benchmarks are written as testcases of their package.
+	SeparateBenchmarks bool
+
+	// Dedent removes the whitespace that all lines of the output of a test
+	// start with before it is written, keeping the indentation of lines
+	// relative to each other. Go indents the output of tests and subtests,
+	// which wastes horizontal space in some UIs. Whitespace is only common if
+	// it matches exactly, so tabs and spaces are not considered equal.
+	Dedent bool
+
+	// Classname, if set, is called with the name of every package to get
+	// the classname of its testcases, e.g. DottedClassname for Java-style
+	// classnames. The package name is passed after ModulePath has been
+	// removed. Classname takes precedence over the classnames used for
+	// Surefire compatibility. By default the package name is used as is.
+	Classname func(pkg string) string
+
+	// TestifySuites writes subtests that look like methods of a testify
+	// suite, i.e. subtests whose name starts with "Test" such as
+	// "TestMySuite/TestMethod", as testcases named after the method with the
+	// suite as their classname. Subtests of methods keep their "/" separated
+	// name, e.g. "TestMethod/case". Use PackageProperty to keep the package
+	// of such testcases available. By default the package is used as the
+	// classname of all testcases.
+	TestifySuites bool
+}
+
+// DefaultTimestampLayout is the timestamp layout used by JUnitWriter unless
+// TimestampLayout is set. It is the same RFC 3339 layout that package junit
+// uses, so both write the same timestamps. Jenkins expects ISO 8601 timestamps
+// without a time zone, which can be written with the "2006-01-02T15:04:05"
+// layout.
+const DefaultTimestampLayout = time.RFC3339
+
+type xmlTestsuites struct {
+	XMLName xml.Name `xml:"testsuites"`
+
+	Tests    int    `xml:"tests,attr"`
+	Failures int    `xml:"failures,attr"`
+	Errors   int    `xml:"errors,attr"`
+	Skipped  int    `xml:"skipped,attr"`
+	Time     string `xml:"time,attr"`
+
+	Suites []xmlTestsuite `xml:"testsuite,omitempty"`
+}
+
+type xmlTestsuite struct {
+	XMLName xml.Name `xml:"testsuite"`
+
+	ID       *int   `xml:"id,attr,omitempty"`
+	Name     string `xml:"name,attr"`
+	Tests    int    `xml:"tests,attr"`
+	Failures int    `xml:"failures,attr"`
+	Errors   int    `xml:"errors,attr"`
+	Skipped  int    `xml:"skipped,attr"`
+	Time     string `xml:"time,attr"`
+
+	Timestamp string `xml:"timestamp,attr,omitempty"`
+
+	Properties *[]xmlProperty `xml:"properties>property,omitempty"`
+	Testcases  []xmlTestcase  `xml:"testcase,omitempty"`
+	Suites     []xmlTestsuite `xml:"testsuite,omitempty"`
+}
+
+type xmlTestcase struct {
+	Name       string `xml:"name,attr"`
+	Classname  string `xml:"classname,attr"`
+	Time       string `xml:"time,attr"`
+	Assertions int    `xml:"assertions,attr,omitempty"`
+	Retries    int    `xml:"retries,attr,omitempty"`
+
+	Properties *[]xmlProperty `xml:"properties>property,omitempty"`
+	Skipped    *xmlResult     `xml:"skipped,omitempty"`
+	Error      *xmlResult     `xml:"error,omitempty"`
+	Failure    *xmlResult     `xml:"failure,omitempty"`
+	SystemOut  *xmlOutput     `xml:"system-out,omitempty"`
+	SystemErr  *xmlOutput     `xml:"system-err,omitempty"`
+}
+
+type xmlProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type xmlResult struct {
+	Message string `xml:"message,attr"`
+	Data    string `xml:",chardata"`
+	CDATA   string `xml:",cdata"`
+}
+
+type xmlOutput struct {
+	Data  string `xml:",chardata"`
+	CDATA string `xml:",cdata"`
+}
+
+// Write writes the JUnit XML representation of report to w.
+func (jw JUnitWriter) Write(w io.Writer, report *Report) error {
+	if err := jw.validate(); err != nil {
+		return err
+	}
+	return jw.encode(w, jw.testsuites(report))
+}
+
+// Convert parses go test json output from r using a parser created with the
+// given options, and writes the JUnit XML report to w. If w is nil, the
+// report is written to os.Stdout. Test output that is echoed while parsing is
+// written to the writer set by the Echo option, os.Stderr by default, and
+// never to w.
+func (jw JUnitWriter) Convert(r io.Reader, w io.Writer, options ...Option) error {
+	if w == nil {
+		w = os.Stdout
+	}
+	report, err := NewParser(options...).Parse(r)
+	if err != nil {
+		return err
+	}
+	return jw.Write(w, report)
+}
+
+// WritePerPackage writes a separate JUnit XML file for each package in report
+// to directory dir, and returns the paths of the files that were written.
+// Files are named after the package, or follow the TEST-<classname>.xml
+// convention when Surefire compatibility is enabled.
+func (jw JUnitWriter) WritePerPackage(dir string, report *Report) ([]string, error) {
+	if err := jw.validate(); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for i, pkg := range report.Packages {
+		var name string
+		var v interface{}
+		if jw.Surefire {
+			name = "TEST-" + jw.classname(pkg) + ".xml"
+			v = jw.testsuite(pkg)
+		} else {
+			name = strings.ReplaceAll(jw.packageName(pkg), "/", "_") + ".xml"
+			v = jw.testsuites(&Report{Packages: report.Packages[i : i+1]})
+		}
+
+		path := filepath.Join(dir, name)
+		if err := jw.writeFile(path, v); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// JUnitStreamWriter writes JUnit XML incrementally, one testsuite at a time.
+// Since the totals of the report are not known until all packages have been
+// written, the root testsuites element written by JUnitStreamWriter has no
+// attributes. Otherwise its output is identical to that of JUnitWriter.
+type JUnitStreamWriter struct {
+	jw      JUnitWriter
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+	err     error // error returned by start, if any
+	n       int   // number of testsuites written
+}
+
+// NewStreamWriter returns a JUnitStreamWriter that writes to w using the
+// configuration of jw. It can be used together with Parser.ParseStream to
+// write each package as soon as it completes:
+//
+//	sw := JUnitWriter{}.NewStreamWriter(w)
+//	if err := parser.ParseStream(r, sw.WritePackage); err != nil {
+//		return err
+//	}
+//	return sw.Close()
+func (jw JUnitWriter) NewStreamWriter(w io.Writer) *JUnitStreamWriter {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	return &JUnitStreamWriter{jw: jw, w: w, enc: enc}
+}
+
+// WritePackage writes pkg as a single testsuite, or as two testsuites if
+// SeparateBenchmarks is enabled and pkg contains benchmarks.
+func (sw *JUnitStreamWriter) WritePackage(pkg *Package) error {
+	if err := sw.start(); err != nil {
+		return err
+	}
+	for _, suite := range sw.jw.packageSuites(pkg) {
+		sw.jw.setID(&suite, sw.n)
+		sw.n++
+		if err := sw.enc.Encode(suite); err != nil {
+			return err
+		}
+	}
+	return sw.enc.Flush()
+}
+
+// Close closes the root testsuites element. It does not close the underlying
+// writer.
+func (sw *JUnitStreamWriter) Close() error {
+	if err := sw.start(); err != nil {
+		return err
+	}
+	if err := sw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "testsuites"}}); err != nil {
+		return err
+	}
+	if err := sw.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(sw.w, "\n")
+	return err
+}
+
+// start writes the XML header and opens the root testsuites element, unless
+// this was already done. If this failed, the same error is returned by every
+// later call.
+func (sw *JUnitStreamWriter) start() error {
+	if !sw.started {
+		sw.started = true
+		sw.err = sw.writeStart()
+	}
+	return sw.err
+}
+
+func (sw *JUnitStreamWriter) writeStart() error {
+	if err := sw.jw.validate(); err != nil {
+		return err
+	}
+	if !sw.jw.SkipXMLHeader {
+		if _, err := io.WriteString(sw.w, xml.Header); err != nil {
+			return err
+		}
+	}
+	return sw.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "testsuites"}})
+}
+
+// validate returns an error if the JUnitWriter configuration is invalid.
+func (jw JUnitWriter) validate() error {
+	if jw.GitLab && jw.NestSubtests {
+		return fmt.Errorf("GitLab and NestSubtests cannot be combined")
+	}
+	if layout := jw.TimestampLayout; layout != "" {
+		// A layout without any recognized elements formats to itself, and a
+		// valid layout must be able to parse what it formatted. The time
+		// that is formatted must differ from the layout reference time in
+		// every field, otherwise numeric layouts also format to themselves.
+		ref := time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC)
+		formatted := ref.Format(layout)
+		if _, err := time.Parse(layout, formatted); err != nil || formatted == layout {
+			return fmt.Errorf("invalid timestamp layout: %q", layout)
+		}
+	}
+	return nil
+}
+
+func (jw JUnitWriter) writeFile(path string, v interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := jw.encode(f, v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (jw JUnitWriter) encode(w io.Writer, v interface{}) error {
+	if !jw.SkipXMLHeader {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (jw JUnitWriter) testsuites(report *Report) xmlTestsuites {
+	var suites xmlTestsuites
+	var duration time.Duration
+	for _, pkg := range report.Packages {
+		for _, suite := range jw.packageSuites(pkg) {
+			jw.setID(&suite, len(suites.Suites))
+			suites.Suites = append(suites.Suites, suite)
+			suites.Tests += suite.Tests
+			suites.Failures += suite.Failures
+			suites.Errors += suite.Errors
+			suites.Skipped += suite.Skipped
+		}
+		duration += pkg.Duration
+	}
+	suites.Time = jw.formatDuration(duration)
+	return suites
+}
+
+// setID sets the id of the given testsuite, if SuiteIDs is enabled.
+func (jw JUnitWriter) setID(suite *xmlTestsuite, id int) {
+	if jw.SuiteIDs && !jw.GitLab {
+		suite.ID = &id
+	}
+}
+
+// packageSuites returns the testsuites for pkg. This is a single testsuite,
+// unless SeparateBenchmarks is enabled and pkg contains benchmarks.
+func (jw JUnitWriter) packageSuites(pkg *Package) []xmlTestsuite {
+	if !jw.SeparateBenchmarks {
+		return []xmlTestsuite{jw.testsuite(pkg)}
+	}
+
+	tests, benchmarks := *pkg, *pkg
+	tests.Tests, benchmarks.Tests = nil, nil
+	benchmarks.Errors = nil
+	var duration time.Duration
+	for _, t := range pkg.Tests {
+		if strings.HasPrefix(t.Name, "Benchmark") {
+			benchmarks.Tests = append(benchmarks.Tests, t)
+			duration += t.Duration
+		} else {
+			tests.Tests = append(tests.Tests, t)
+		}
+	}
+	suites := []xmlTestsuite{jw.testsuite(&tests)}
+	if len(benchmarks.Tests) > 0 {
+		suite := jw.testsuite(&benchmarks)
+		suite.Name += " [bench]"
+		suite.Time = jw.formatDuration(duration)
+		suites = append(suites, suite)
+	}
+	return suites
+}
+
+func (jw JUnitWriter) testsuite(pkg *Package) xmlTestsuite {
+	suite := xmlTestsuite{
+		Name: jw.packageName(pkg),
+		Time: jw.formatDuration(pkg.Duration),
+	}
+
+	if !pkg.Timestamp.IsZero() {
+		layout := jw.TimestampLayout
+		if layout == "" {
+			layout = DefaultTimestampLayout
+		}
+		suite.Timestamp = pkg.Timestamp.Format(layout)
+	}
+
+	if pkg.CoveragePct != "" {
+		suite.addProperty("coverage.statements.pct", pkg.CoveragePct)
+	}
+	if jw.Command != "" {
+		suite.addProperty("go.command", jw.Command)
+	}
+
+	var nested map[string][]*Test
+	if jw.NestSubtests {
+		nested = subtests(pkg.Tests)
+	}
+	for _, test := range pkg.Tests {
+		if parent := parentName(test.Name); parent != test.Name {
+			if _, ok := nested[parent]; ok {
+				continue // written in the nested testsuite of its parent
+			}
+		}
+		if subs, ok := nested[test.Name]; ok {
+			suite.addTestsuite(jw.subtestsuite(pkg, test, subs))
+			continue
+		}
+		suite.addTestcase(jw.testcase(pkg, test))
+	}
+	for _, e := range pkg.Errors {
+		suite.addTestcase(jw.errorTestcase(pkg, e))
+	}
+
+	if jw.GitLab {
+		suite.Timestamp = ""
+		suite.Properties = nil
+	}
+	return suite
+}
+
+// subtestsuite returns the nested testsuite for the top-level test parent
+// and its subtests subs, see NestSubtests.
+func (jw JUnitWriter) subtestsuite(pkg *Package, parent *Test, subs []*Test) xmlTestsuite {
+	suite := xmlTestsuite{
+		Name: parent.Name,
+		Time: jw.formatDuration(parent.Duration),
+	}
+	for _, test := range subs {
+		suite.addTestcase(jw.testcase(pkg, test))
+	}
+	if parent.Result == FAIL && suite.Failures == 0 && suite.Errors == 0 {
+		suite.addTestcase(jw.testcase(pkg, parent))
+	}
+	return suite
+}
+
+// addProperty adds a property with the given name and value to this
+// testsuite.
+func (suite *xmlTestsuite) addProperty(name, value string) {
+	prop := xmlProperty{Name: name, Value: value}
+	if suite.Properties == nil {
+		suite.Properties = &[]xmlProperty{prop}
+		return
+	}
+	props := append(*suite.Properties, prop)
+	suite.Properties = &props
+}
+
+// addTestcase adds tc to this testsuite and updates its counts.
+func (suite *xmlTestsuite) addTestcase(tc xmlTestcase) {
+	suite.Testcases = append(suite.Testcases, tc)
+	suite.Tests++
+	if tc.Error != nil {
+		suite.Errors++
+	}
+	if tc.Failure != nil {
+		suite.Failures++
+	}
+	if tc.Skipped != nil {
+		suite.Skipped++
+	}
+}
+
+// addTestsuite nests s in this testsuite and adds its counts to those of
+// this testsuite.
+func (suite *xmlTestsuite) addTestsuite(s xmlTestsuite) {
+	suite.Suites = append(suite.Suites, s)
+	suite.Tests += s.Tests
+	suite.Errors += s.Errors
+	suite.Failures += s.Failures
+	suite.Skipped += s.Skipped
+}
+
+// subtests returns the subtests in tests grouped by the name of their
+// top-level test. Subtests whose top-level test is not in tests are left out.
+func subtests(tests []*Test) map[string][]*Test {
+	parents := make(map[string]bool)
+	for _, t := range tests {
+		parents[t.Name] = true
+	}
+
+	m := make(map[string][]*Test)
+	for _, t := range tests {
+		if parent := parentName(t.Name); parent != t.Name && parents[parent] {
+			m[parent] = append(m[parent], t)
+		}
+	}
+	return m
+}
+
+// parentName returns the name of the top-level test of the test with the
+// given name.
+func parentName(name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func (jw JUnitWriter) testcase(pkg *Package, test *Test) xmlTestcase {
+	tc := xmlTestcase{
+		Name:       test.Name,
+		Classname:  jw.classname(pkg),
+		Time:       jw.formatDuration(test.Duration),
+		Assertions: test.Assertions,
+	}
+
+	// Attempts are only tracked with the TrackAttempts option, the result
+	// of the testcase is that of the last attempt.
+	if n := len(test.Attempts); n > 1 {
+		tc.Retries = n - 1
+	}
+
+	if jw.NonZeroDurations && tc.Time == jw.formatDuration(0) {
+		min := time.Millisecond
+		if jw.DurationRounding > 0 {
+			min = jw.DurationRounding
+		}
+		tc.Time = jw.formatDuration(min)
+	}
+
+	if jw.TestifySuites {
+		if suite, method, ok := testifySuiteMethod(test.Name); ok {
+			tc.Classname, tc.Name = suite, method
+		}
+	}
+
+	if jw.SubtestSeparator != "" && strings.Contains(tc.Name, "/") {
+		tc.Name = strings.ReplaceAll(tc.Name, "/", jw.SubtestSeparator)
+		tc.addProperty("test.name", test.Name)
+	}
+
+	if jw.PackageProperty {
+		tc.addProperty("package", pkg.Name)
+	}
+
+	if jw.TestIDProperty {
+		tc.addProperty("id", test.ID())
+	}
+
+	if test.Owner != "" {
+		tc.addProperty("owner", test.Owner)
+	}
+
+	if jw.LogProperties {
+		for _, line := range test.Output {
+			for _, kv := range logProperties(line) {
+				tc.addProperty(kv[0], kv[1])
+			}
+		}
+	}
+
+	switch {
+	case test.Orphaned && test.Incomplete:
+		tc.Error = &xmlResult{Message: orphanedMessage, Data: jw.formatOutput(test)}
+	case test.Incomplete:
+		tc.Error = &xmlResult{Message: "No test result found", Data: jw.formatOutput(test)}
+	case test.LeakDetected:
+		tc.Error = &xmlResult{Message: "Leaked goroutines", Data: jw.formatOutput(test)}
+	case test.Result == FAIL:
+		tc.Failure = &xmlResult{Message: "Failed", Data: elide(jw.formatOutput(test), jw.MaxFailureBytes)}
+	case test.Orphaned && test.Result == SKIP:
+		tc.Skipped = &xmlResult{Message: orphanedMessage, Data: jw.formatOutput(test)}
+	case test.Result == SKIP:
+		tc.Skipped = &xmlResult{Message: "Skipped", Data: jw.formatOutput(test)}
+	case jw.IncludePassingOutput && len(test.Output) > 0:
+		tc.SystemOut = &xmlOutput{Data: jw.formatOutput(test)}
+	}
+
+	if len(test.Stderr) > 0 {
+		tc.SystemErr = &xmlOutput{Data: strings.Join(test.Stderr, "")}
+	}
+
+	if jw.GitLab {
+		tc.Properties = nil
+		if tc.Skipped != nil && tc.Skipped.Data != "" {
+			tc.SystemOut = &xmlOutput{Data: tc.Skipped.Data}
+			tc.Skipped.Data = ""
+		}
+	}
+
+	if jw.CDATA {
+		for _, r := range []*xmlResult{tc.Skipped, tc.Error, tc.Failure} {
+			if r != nil {
+				r.CDATA, r.Data = validXMLString(r.Data), ""
+			}
+		}
+		for _, o := range []*xmlOutput{tc.SystemOut, tc.SystemErr} {
+			if o != nil {
+				o.CDATA, o.Data = validXMLString(o.Data), ""
+			}
+		}
+	}
+	return tc
+}
+
+// testifySuiteMethod splits the name of a test that looks like a method of a
+// testify suite, e.g. "TestMySuite/TestMethod", into the name of the suite and
+// the method including any of its subtests.
+func testifySuiteMethod(name string) (suite, method string, ok bool) {
+	i := strings.Index(name, "/")
+	if i < 0 || !strings.HasPrefix(name[i+1:], "Test") {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// orphanedMessage is the message of testcases for orphaned subtests, see
+// Test.Orphaned.
+const orphanedMessage = "Parent test completed before subtest"
+
+// errorTestcase returns a synthetic errored testcase for the package error e,
+// named after the phase in which the package failed.
+func (jw JUnitWriter) errorTestcase(pkg *Package, e PackageError) xmlTestcase {
+	tc := xmlTestcase{
+		Name:      e.Phase,
+		Classname: jw.classname(pkg),
+		Time:      jw.formatDuration(0),
+	}
+	message := "Package failed during " + e.Phase
+	if jw.CDATA {
+		tc.Error = &xmlResult{Message: message, CDATA: validXMLString(strings.Join(e.Output, ""))}
+	} else {
+		tc.Error = &xmlResult{Message: message, Data: strings.Join(e.Output, "")}
+	}
+	return tc
+}
+
+// validXMLString returns s with all characters that cannot be represented in
+// XML replaced by U+FFFD. Unlike character data, CDATA sections are not
+// sanitized by the encoder.
+func validXMLString(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' ||
+			r >= 0x20 && r <= 0xD7FF ||
+			r >= 0xE000 && r <= 0xFFFD ||
+			r >= 0x10000 && r <= 0x10FFFF {
+			return r
+		}
+		return '\uFFFD'
+	}, s)
+}
+
+// addProperty adds a property with the given name and value to this testcase.
+func (tc *xmlTestcase) addProperty(name, value string) {
+	prop := xmlProperty{Name: name, Value: value}
+	if tc.Properties == nil {
+		tc.Properties = &[]xmlProperty{prop}
+		return
+	}
+	props := append(*tc.Properties, prop)
+	tc.Properties = &props
+}
+
+var (
+	// regexLogLocation matches the file and line location that t.Log adds
+	// to the start of every line it logs.
+	regexLogLocation = regexp.MustCompile(`^[^\s:]+\.go:\d+: `)
+
+	// regexLogProperties matches a line consisting of only key=value pairs,
+	// and regexLogProperty matches a single pair.
+	regexLogProperties = regexp.MustCompile(`^(?:[A-Za-z_][\w.-]*=(?:"(?:[^"\\]|\\.)*"|[^\s"]+)(?:\s+|$))+$`)
+	regexLogProperty   = regexp.MustCompile(`([A-Za-z_][\w.-]*)=("(?:[^"\\]|\\.)*"|[^\s"]+)`)
+)
+
+// logProperties returns the key=value pairs in a line of test output, or nil
+// if the line is not made up entirely of key=value pairs.
+func logProperties(line string) [][2]string {
+	line = strings.TrimSpace(line)
+	line = regexLogLocation.ReplaceAllString(line, "")
+	if !regexLogProperties.MatchString(line) {
+		return nil
+	}
+
+	var props [][2]string
+	for _, m := range regexLogProperty.FindAllStringSubmatch(line, -1) {
+		value := m[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		props = append(props, [2]string{m[1], value})
+	}
+	return props
+}
+
+// classname returns the classname to use for tests in the given package.
+func (jw JUnitWriter) classname(pkg *Package) string {
+	name := jw.packageName(pkg)
+	if jw.Classname != nil {
+		return jw.Classname(name)
+	}
+	if jw.Surefire {
+		return DottedClassname(name)
+	}
+	return name
+}
+
+// DottedClassname returns the Java-style classname for the package with the
+// given name, e.g. "github.com.org.repo.pkg" for "github.com/org/repo/pkg".
+// Empty segments, such as those caused by leading, trailing or repeated
+// slashes, are left out.
+func DottedClassname(pkg string) string {
+	var segments []string
+	for _, s := range strings.Split(pkg, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// packageName returns the name of pkg as it should appear in the report.
+func (jw JUnitWriter) packageName(pkg *Package) string {
+	if jw.ModulePath != "" {
+		prefix := strings.TrimSuffix(jw.ModulePath, "/") + "/"
+		if strings.HasPrefix(pkg.Name, prefix) && len(pkg.Name) > len(prefix) {
+			return pkg.Name[len(prefix):]
+		}
+	}
+	return pkg.Name
+}
+
+// formatDuration returns the JUnit string representation of the given
+// duration, rounded according to DurationRounding.
+func (jw JUnitWriter) formatDuration(d time.Duration) string {
+	unit := jw.DurationRounding
+	if unit <= 0 {
+		return formatDuration(d)
+	}
+	decimals := 3
+	for div := time.Millisecond; decimals < 9 && unit%div != 0; div /= 10 {
+		decimals++
+	}
+	return strconv.FormatFloat(d.Round(unit).Seconds(), 'f', decimals, 64)
+}
+
+// formatDuration returns the JUnit string representation of the given
+// duration.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// formatOutput combines the output chunks of a test into a single string.
+// Output chunks already contain their trailing newlines.
+func (jw JUnitWriter) formatOutput(test *Test) string {
+	output := test.Output
+	if jw.StripSummaryLines {
+		output = stripSummaryLines(output)
+	}
+	if jw.Dedent {
+		return dedent(strings.Join(output, ""))
+	}
+	return strings.Join(output, "")
+}
+
+// dedent removes the longest common leading whitespace from all lines of s.
+// Lines that contain only whitespace are not taken into account and keep any
+// whitespace beyond the common prefix.
+func dedent(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	prefix, found := "", false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		n := 0
+		for n < len(prefix) && n < len(indent) && prefix[n] == indent[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	if prefix == "" {
+		return s
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+	}
+	return strings.Join(lines, "")
+}
+
+// elide returns s with its middle replaced by an elision marker if it is
+// longer than max bytes, keeping the start and end of s. Both parts are cut
+// at rune boundaries, so they may be slightly shorter than max/2 bytes.
+func elide(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	head := max / 2
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	tail := len(s) - (max - max/2)
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+	return fmt.Sprintf("%s\n... [%d bytes elided] ...\n%s", s[:head], tail-head, s[tail:])
+}
+
+// regexStatus matches the PASS or FAIL status line printed at the end of a
+// test binary run.
+var regexStatus = regexp.MustCompile(`^(?:PASS|FAIL)$`)
+
+// stripSummaryLines returns output without status and package summary lines.
+func stripSummaryLines(output []string) []string {
+	var stripped []string
+	for _, line := range output {
+		trimmed := strings.TrimSpace(line)
+		if regexStatus.MatchString(trimmed) || regexSummary.MatchString(trimmed) {
+			continue
+		}
+		stripped = append(stripped, line)
+	}
+	return stripped
+}
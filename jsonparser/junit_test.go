@@ -0,0 +1,980 @@
+package jsonparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJUnitWriterCompletionOrder(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestZebra"}
+{"Action":"output","Package":"package/name","Test":"TestZebra","Output":"=== PAUSE TestZebra\n"}
+{"Action":"run","Package":"package/name","Test":"TestApple"}
+{"Action":"output","Package":"package/name","Test":"TestApple","Output":"=== PAUSE TestApple\n"}
+{"Action":"run","Package":"package/name","Test":"TestMango"}
+{"Action":"pass","Package":"package/name","Test":"TestMango","Elapsed":0.01}
+{"Action":"pass","Package":"package/name","Test":"TestZebra","Elapsed":0.02}
+{"Action":"pass","Package":"package/name","Test":"TestApple","Elapsed":0.03}
+{"Action":"pass","Package":"package/name","Elapsed":0.04}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"TestMango", "TestZebra", "TestApple"}
+	if diff := cmp.Diff(want, writtenTestcaseNames(t, JUnitWriter{}, report)); diff != "" {
+		t.Errorf("unexpected testcase order, diff (-want, +got):\n%s\n", diff)
+	}
+
+	report.Sort()
+	want = []string{"TestApple", "TestMango", "TestZebra"}
+	if diff := cmp.Diff(want, writtenTestcaseNames(t, JUnitWriter{}, report)); diff != "" {
+		t.Errorf("unexpected testcase order after Sort, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+// writtenTestcaseNames writes the report using jw and returns the names of all
+// testcases in the order they appear in the XML output.
+func writtenTestcaseNames(t *testing.T, jw JUnitWriter, report *Report) []string {
+	var buf bytes.Buffer
+	if err := jw.Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := ValidateJUnitXML(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("invalid JUnit XML written: %v\n%s", err, buf.String())
+	}
+
+	var suites xmlTestsuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, buf.String())
+	}
+
+	var names []string
+	for _, suite := range suites.Suites {
+		for _, tc := range suite.Testcases {
+			names = append(names, tc.Name)
+		}
+	}
+	return names
+}
+
+func TestJUnitWriterIncomplete(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestFail", Result: FAIL},
+			{Name: "TestKilled", Result: FAIL, Incomplete: true, Output: []string{"=== RUN   TestKilled\n"}},
+		},
+	}}}
+
+	suites := JUnitWriter{}.testsuites(report)
+	if got := suites.Suites[0]; got.Failures != 1 || got.Errors != 1 {
+		t.Errorf("unexpected testsuite counts, got failures=%d errors=%d, want failures=1 errors=1", got.Failures, got.Errors)
+	}
+	want := &xmlResult{Message: "No test result found", Data: "=== RUN   TestKilled\n"}
+	if diff := cmp.Diff(want, suites.Suites[0].Testcases[1].Error); diff != "" {
+		t.Errorf("unexpected error for incomplete test, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestJUnitWriterSuiteIDs(t *testing.T) {
+	report := &Report{Packages: []*Package{{Name: "package/a"}, {Name: "package/b"}, {Name: "package/c"}}}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{}).Write(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), ` id="`) {
+		t.Errorf("id attribute written without SuiteIDs enabled:\n%s", buf.String())
+	}
+
+	suites := JUnitWriter{SuiteIDs: true}.testsuites(report)
+	for i, suite := range suites.Suites {
+		if suite.ID == nil || *suite.ID != i {
+			t.Errorf("testsuite %s has unexpected id, got %v, want %d", suite.Name, suite.ID, i)
+		}
+	}
+
+	buf.Reset()
+	if err := (JUnitWriter{SuiteIDs: true}).Write(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<testsuite id="0" name="package/a"`) {
+		t.Errorf("expected first testsuite to have id 0, got:\n%s", buf.String())
+	}
+}
+
+func TestJUnitWriterEscapeNames(t *testing.T) {
+	name := "TestThing/case_with_<brackets>_&_\"quotes\"_and\tcontrol\x01\x1b"
+	report := &Report{Packages: []*Package{{
+		Name:  "package/<name>&",
+		Tests: []*Test{{Name: name, Result: FAIL, Output: []string{"got <nil> & \"\x02\"\n"}}},
+	}}}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{}).Write(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+
+	var suites xmlTestsuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, buf.String())
+	}
+
+	tc := suites.Suites[0].Testcases[0]
+	if want := "TestThing/case_with_<brackets>_&_\"quotes\"_and\tcontrol��"; tc.Name != want {
+		t.Errorf("unexpected testcase name, got %q, want %q", tc.Name, want)
+	}
+	if want := "package/<name>&"; tc.Classname != want {
+		t.Errorf("unexpected testcase classname, got %q, want %q", tc.Classname, want)
+	}
+	if want := "got <nil> & \"�\"\n"; tc.Failure == nil || tc.Failure.Data != want {
+		t.Errorf("unexpected failure output, got %+v, want %q", tc.Failure, want)
+	}
+}
+
+func TestJUnitWriterSurefire(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "github.com/org/repo/a", Tests: []*Test{{Name: "TestA", Result: PASS}}},
+		{Name: "github.com/org/repo/b", Tests: []*Test{{Name: "TestB", Result: FAIL}}},
+	}}
+
+	dir := t.TempDir()
+	files, err := JUnitWriter{Surefire: true}.WritePerPackage(dir, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "TEST-github.com.org.repo.a.xml"),
+		filepath.Join(dir, "TEST-github.com.org.repo.b.xml"),
+	}
+	if diff := cmp.Diff(want, files); diff != "" {
+		t.Fatalf("unexpected files written, diff (-want, +got):\n%s\n", diff)
+	}
+
+	data, err := os.ReadFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suite xmlTestsuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, data)
+	}
+	if suite.XMLName.Local != "testsuite" {
+		t.Errorf("unexpected root element, got %q, want %q", suite.XMLName.Local, "testsuite")
+	}
+	if suite.Name != "github.com/org/repo/b" || suite.Tests != 1 || suite.Failures != 1 {
+		t.Errorf("unexpected testsuite, got name=%q tests=%d failures=%d", suite.Name, suite.Tests, suite.Failures)
+	}
+	if got, want := suite.Testcases[0].Classname, "github.com.org.repo.b"; got != want {
+		t.Errorf("unexpected classname, got %q, want %q", got, want)
+	}
+}
+
+func TestJUnitWriterIncludePassingOutput(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestPass", Result: PASS, Output: []string{"pass output\n"}},
+			{Name: "TestFail", Result: FAIL, Output: []string{"fail output\n"}},
+		},
+	}}}
+
+	for _, enabled := range []bool{false, true} {
+		testcases := JUnitWriter{IncludePassingOutput: enabled}.testsuites(report).Suites[0].Testcases
+
+		var want *xmlOutput
+		if enabled {
+			want = &xmlOutput{Data: "pass output\n"}
+		}
+		if diff := cmp.Diff(want, testcases[0].SystemOut); diff != "" {
+			t.Errorf("IncludePassingOutput=%v: unexpected system-out for passing test, diff (-want, +got):\n%s\n", enabled, diff)
+		}
+		if testcases[1].SystemOut != nil {
+			t.Errorf("IncludePassingOutput=%v: unexpected system-out for failing test: %+v", enabled, testcases[1].SystemOut)
+		}
+	}
+}
+
+func TestJUnitWriterTimestampLayout(t *testing.T) {
+	ts := time.Date(2022, 6, 26, 13, 14, 15, 123456789, time.UTC)
+	report := &Report{Packages: []*Package{{Name: "package/name", Timestamp: ts}}}
+
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{"", "2022-06-26T13:14:15Z"},
+		{DefaultTimestampLayout, "2022-06-26T13:14:15Z"},
+		{"2006-01-02T15:04:05", "2022-06-26T13:14:15"},
+		{"2006-01-02 15:04:05", "2022-06-26 13:14:15"},
+		{time.RFC3339, "2022-06-26T13:14:15Z"},
+		{time.RFC3339Nano, "2022-06-26T13:14:15.123456789Z"},
+	}
+
+	for _, test := range tests {
+		jw := JUnitWriter{TimestampLayout: test.layout}
+		if err := jw.validate(); err != nil {
+			t.Errorf("validate(%q) returned unexpected error: %v", test.layout, err)
+		}
+		if got := jw.testsuites(report).Suites[0].Timestamp; got != test.want {
+			t.Errorf("unexpected timestamp for layout %q, got %q, want %q", test.layout, got, test.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{TimestampLayout: "not a layout"}).Write(&buf, report); err == nil {
+		t.Errorf("Write with invalid timestamp layout did not return an error, wrote:\n%s", buf.String())
+	}
+}
+
+func TestJUnitStreamWriter(t *testing.T) {
+	input := `{"Time":"2022-01-01T10:00:00Z","Action":"run","Package":"package/a","Test":"TestA"}
+{"Time":"2022-01-01T10:00:00Z","Action":"run","Package":"package/b","Test":"TestB"}
+{"Time":"2022-01-01T10:00:00Z","Action":"output","Package":"package/b","Test":"TestB","Output":"b failed\n"}
+{"Time":"2022-01-01T10:00:01Z","Action":"fail","Package":"package/b","Test":"TestB","Elapsed":1}
+{"Time":"2022-01-01T10:00:01Z","Action":"fail","Package":"package/b","Elapsed":1}
+{"Time":"2022-01-01T10:00:02Z","Action":"pass","Package":"package/a","Test":"TestA","Elapsed":2}
+{"Time":"2022-01-01T10:00:02Z","Action":"pass","Package":"package/a","Elapsed":2}
+{"Time":"2022-01-01T10:00:02Z","Action":"run","Package":"package/c","Test":"TestC"}
+`
+	jw := JUnitWriter{SuiteIDs: true}
+
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batch bytes.Buffer
+	if err := jw.Write(&batch, report); err != nil {
+		t.Fatal(err)
+	}
+
+	var stream bytes.Buffer
+	sw := jw.NewStreamWriter(&stream)
+	var written []int
+	err = NewParser().ParseStream(strings.NewReader(input), func(pkg *Package) error {
+		if err := sw.WritePackage(pkg); err != nil {
+			return err
+		}
+		written = append(written, stream.Len())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every package must have been written before the next one completed.
+	if len(written) != 3 || written[0] == 0 || written[0] >= written[1] || written[1] >= written[2] {
+		t.Errorf("packages were not written incrementally, output sizes after each package: %v", written)
+	}
+
+	var want, got xmlTestsuites
+	if err := xml.Unmarshal(batch.Bytes(), &want); err != nil {
+		t.Fatalf("invalid XML written by JUnitWriter: %v\n%s", err, batch.String())
+	}
+	if err := xml.Unmarshal(stream.Bytes(), &got); err != nil {
+		t.Fatalf("invalid XML written by JUnitStreamWriter: %v\n%s", err, stream.String())
+	}
+	if diff := cmp.Diff(want.Suites, got.Suites); diff != "" {
+		t.Errorf("streamed testsuites differ from batch testsuites, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestJUnitStreamWriterInvalidConfig(t *testing.T) {
+	var buf bytes.Buffer
+	sw := JUnitWriter{GitLab: true, NestSubtests: true}.NewStreamWriter(&buf)
+	if err := sw.WritePackage(&Package{Name: "package/name"}); err == nil {
+		t.Errorf("WritePackage did not return an error for an invalid configuration")
+	}
+	if err := sw.WritePackage(&Package{Name: "package/name"}); err == nil {
+		t.Errorf("second WritePackage did not return an error for an invalid configuration")
+	}
+	if err := sw.Close(); err == nil {
+		t.Errorf("Close did not return an error for an invalid configuration")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output written for an invalid configuration:\n%s", buf.String())
+	}
+}
+
+func TestJUnitWriterSubtestSeparator(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestParent", Result: PASS},
+			{Name: "TestParent/Child", Result: PASS},
+			{Name: "TestParent/Child/Grandchild", Result: PASS},
+		},
+	}}}
+
+	if diff := cmp.Diff([]string{"TestParent", "TestParent/Child", "TestParent/Child/Grandchild"}, writtenTestcaseNames(t, JUnitWriter{}, report)); diff != "" {
+		t.Errorf("unexpected default testcase names, diff (-want, +got):\n%s\n", diff)
+	}
+
+	jw := JUnitWriter{SubtestSeparator: " › "}
+	if diff := cmp.Diff([]string{"TestParent", "TestParent › Child", "TestParent › Child › Grandchild"}, writtenTestcaseNames(t, jw, report)); diff != "" {
+		t.Errorf("unexpected testcase names with custom separator, diff (-want, +got):\n%s\n", diff)
+	}
+
+	testcases := jw.testsuites(report).Suites[0].Testcases
+	if testcases[0].Properties != nil {
+		t.Errorf("unexpected properties for test without subtest separator: %v", *testcases[0].Properties)
+	}
+	want := &[]xmlProperty{{Name: "test.name", Value: "TestParent/Child/Grandchild"}}
+	if diff := cmp.Diff(want, testcases[2].Properties); diff != "" {
+		t.Errorf("unexpected properties for renamed test, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestJUnitWriterStripSummaryLines(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{{Name: "TestFail", Result: FAIL, Output: []string{
+			"=== RUN   TestFail\n",
+			"    main_test.go:6: got 1, want 2\n",
+			"--- FAIL: TestFail (0.00s)\n",
+			"FAIL\n",
+			"FAIL\tpackage/name\t0.012s\n",
+			"ok  \tpackage/other\t(cached)\n",
+		}}},
+	}}}
+
+	tests := []struct {
+		strip bool
+		want  string
+	}{
+		{false, "=== RUN   TestFail\n    main_test.go:6: got 1, want 2\n--- FAIL: TestFail (0.00s)\nFAIL\nFAIL\tpackage/name\t0.012s\nok  \tpackage/other\t(cached)\n"},
+		{true, "=== RUN   TestFail\n    main_test.go:6: got 1, want 2\n--- FAIL: TestFail (0.00s)\n"},
+	}
+
+	for _, test := range tests {
+		tc := JUnitWriter{StripSummaryLines: test.strip}.testsuites(report).Suites[0].Testcases[0]
+		if diff := cmp.Diff(test.want, tc.Failure.Data); diff != "" {
+			t.Errorf("StripSummaryLines=%v: unexpected failure output, diff (-want, +got):\n%s\n", test.strip, diff)
+		}
+	}
+}
+
+func TestJUnitWriterModulePath(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "github.com/org/repo/service/foo", Tests: []*Test{{Name: "TestFoo", Result: PASS}}},
+		{Name: "github.com/org/repository/bar", Tests: []*Test{{Name: "TestBar", Result: PASS}}},
+		{Name: "github.com/org/repo", Tests: []*Test{{Name: "TestRoot", Result: PASS}}},
+	}}
+
+	tests := []struct {
+		modulePath string
+		want       []string
+	}{
+		{"", []string{"github.com/org/repo/service/foo", "github.com/org/repository/bar", "github.com/org/repo"}},
+		{"github.com/org/repo", []string{"service/foo", "github.com/org/repository/bar", "github.com/org/repo"}},
+		{"github.com/org/repo/", []string{"service/foo", "github.com/org/repository/bar", "github.com/org/repo"}},
+		{"example.com/other", []string{"github.com/org/repo/service/foo", "github.com/org/repository/bar", "github.com/org/repo"}},
+	}
+
+	for _, test := range tests {
+		suites := JUnitWriter{ModulePath: test.modulePath}.testsuites(report)
+		for i, suite := range suites.Suites {
+			if suite.Name != test.want[i] {
+				t.Errorf("ModulePath=%q: unexpected testsuite name, got %q, want %q", test.modulePath, suite.Name, test.want[i])
+			}
+			if got := suite.Testcases[0].Classname; got != test.want[i] {
+				t.Errorf("ModulePath=%q: unexpected classname, got %q, want %q", test.modulePath, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestJUnitWriterNonZeroDurations(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestZero", Result: PASS},
+			{Name: "TestTiny", Result: PASS, Duration: 100 * time.Microsecond},
+			{Name: "TestSlow", Result: PASS, Duration: 1500 * time.Millisecond},
+		},
+	}}}
+
+	tests := []struct {
+		enabled  bool
+		rounding time.Duration
+		want     []string
+	}{
+		{false, 0, []string{"0.000", "0.000", "1.500"}},
+		{true, 0, []string{"0.001", "0.001", "1.500"}},
+		{true, time.Second, []string{"1.000", "1.000", "2.000"}},
+		{true, time.Microsecond, []string{"0.000001", "0.000100", "1.500000"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		jw := JUnitWriter{NonZeroDurations: test.enabled, DurationRounding: test.rounding}
+		for _, tc := range jw.testsuites(report).Suites[0].Testcases {
+			got = append(got, tc.Time)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("NonZeroDurations=%v, DurationRounding=%v: unexpected testcase times, diff (-want, +got):\n%s\n", test.enabled, test.rounding, diff)
+		}
+	}
+}
+
+func TestJUnitWriterNestSubtests(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name:     "package/name",
+		Duration: 2 * time.Second,
+		Tests: []*Test{
+			{Name: "TestParent/one", Result: PASS},
+			{Name: "TestParent/two", Result: FAIL},
+			{Name: "TestParent/three", Result: SKIP},
+			{Name: "TestParent", Result: FAIL, Duration: time.Second},
+			{Name: "TestSingle", Result: PASS},
+		},
+	}}}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{NestSubtests: true}).Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	var suites xmlTestsuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, buf.String())
+	}
+
+	type suite struct {
+		Name                             string
+		Tests, Failures, Skipped, Nested int
+		Testcases                        []string
+	}
+	summarize := func(s xmlTestsuite) suite {
+		got := suite{Name: s.Name, Tests: s.Tests, Failures: s.Failures, Skipped: s.Skipped, Nested: len(s.Suites)}
+		for _, tc := range s.Testcases {
+			got.Testcases = append(got.Testcases, tc.Name)
+		}
+		return got
+	}
+
+	if len(suites.Suites) != 1 {
+		t.Fatalf("unexpected number of testsuites, got %d, want 1", len(suites.Suites))
+	}
+	pkg := suites.Suites[0]
+	if diff := cmp.Diff(suite{"package/name", 4, 1, 1, 1, []string{"TestSingle"}}, summarize(pkg)); diff != "" {
+		t.Errorf("unexpected package testsuite, diff (-want, +got):\n%s\n", diff)
+	}
+	if len(pkg.Suites) != 1 {
+		t.Fatalf("unexpected number of nested testsuites, got %d, want 1", len(pkg.Suites))
+	}
+	want := suite{"TestParent", 3, 1, 1, 0, []string{"TestParent/one", "TestParent/two", "TestParent/three"}}
+	if diff := cmp.Diff(want, summarize(pkg.Suites[0])); diff != "" {
+		t.Errorf("unexpected nested testsuite, diff (-want, +got):\n%s\n", diff)
+	}
+	if got, want := pkg.Suites[0].Time, "1.000"; got != want {
+		t.Errorf("unexpected nested testsuite time, got %q, want %q", got, want)
+	}
+}
+
+func TestJUnitWriterMaxFailureBytes(t *testing.T) {
+	output := []string{"main_test.go:10: values differ\n"}
+	for i := 0; i < 100; i++ {
+		output = append(output, "    diff line\n")
+	}
+	output = append(output, "--- FAIL: TestDiff (0.00s)\n")
+
+	report := &Report{Packages: []*Package{{
+		Name:  "package/name",
+		Tests: []*Test{{Name: "TestDiff", Result: FAIL, Output: output}},
+	}}}
+
+	got := (JUnitWriter{MaxFailureBytes: 100}).testsuites(report).Suites[0].Testcases[0].Failure.Data
+	if !strings.HasPrefix(got, "main_test.go:10: values differ\n") {
+		t.Errorf("head of failure body was not kept, got:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "--- FAIL: TestDiff (0.00s)\n") {
+		t.Errorf("tail of failure body was not kept, got:\n%s", got)
+	}
+	full := strings.Join(output, "")
+	marker := fmt.Sprintf("\n... [%d bytes elided] ...\n", len(full)-100)
+	if !strings.Contains(got, marker) {
+		t.Errorf("failure body does not contain elision marker %q, got:\n%s", marker, got)
+	}
+	if want := 100 + len(marker); len(got) != want {
+		t.Errorf("unexpected failure body length, got %d, want %d", len(got), want)
+	}
+
+	if got := (JUnitWriter{}).testsuites(report).Suites[0].Testcases[0].Failure.Data; got != full {
+		t.Errorf("failure body was modified without MaxFailureBytes, got:\n%s", got)
+	}
+}
+
+func TestElideRuneBoundaries(t *testing.T) {
+	got := elide("ééééé", 5) // 10 bytes
+	if !utf8.ValidString(got) {
+		t.Errorf("elide produced invalid UTF-8: %q", got)
+	}
+}
+
+func TestJUnitWriterGitLab(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name:        "package/name",
+		Duration:    1500 * time.Millisecond,
+		CoveragePct: "80.0",
+		Timestamp:   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tests: []*Test{
+			{Name: "TestPass", Result: PASS, Duration: 100 * time.Millisecond, Output: []string{"passing output\n"}},
+			{Name: "TestFail", Result: FAIL, Duration: 200 * time.Millisecond, Output: []string{"    main_test.go:10: got 1, want 2\n"}},
+			{Name: "TestSkip", Result: SKIP, Output: []string{"    main_test.go:20: not supported\n"}},
+			{Name: "TestKilled", Result: FAIL, Incomplete: true, Output: []string{"=== RUN   TestKilled\n"}},
+			{Name: "TestParent/sub", Result: PASS},
+		},
+	}}}
+
+	jw := JUnitWriter{GitLab: true, SuiteIDs: true, SubtestSeparator: "."}
+	var buf bytes.Buffer
+	if err := jw.Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	want, err := os.ReadFile("testdata/gitlab-report.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), buf.String()); diff != "" {
+		t.Errorf("unexpected GitLab report, diff (-want, +got):\n%s\n", diff)
+	}
+
+	if err := (JUnitWriter{GitLab: true, NestSubtests: true}).Write(&buf, report); err == nil {
+		t.Errorf("Write with GitLab and NestSubtests did not return an error")
+	}
+}
+
+func TestJUnitWriterLogProperties(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{{
+			Name:   "TestLogin",
+			Result: FAIL,
+			Output: []string{
+				"=== RUN   TestLogin\n",
+				"    login_test.go:12: user=alice attempt=3\n",
+				"    login_test.go:13: got status=500, want 200\n",
+				"    login_test.go:14: a == b\n",
+				"    login_test.go:15: =value\n",
+				"request_id=abc-123 msg=\"login failed\"\n",
+				"--- FAIL: TestLogin (0.00s)\n",
+			},
+		}},
+	}}}
+
+	type property struct{ Name, Value string }
+	tests := []struct {
+		enabled bool
+		want    []property
+	}{
+		{false, nil},
+		{true, []property{
+			{"user", "alice"},
+			{"attempt", "3"},
+			{"request_id", "abc-123"},
+			{"msg", "login failed"},
+		}},
+	}
+
+	for _, test := range tests {
+		tc := (JUnitWriter{LogProperties: test.enabled}).testsuites(report).Suites[0].Testcases[0]
+		var got []property
+		if tc.Properties != nil {
+			for _, p := range *tc.Properties {
+				got = append(got, property{p.Name, p.Value})
+			}
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("LogProperties=%v: unexpected properties, diff (-want, +got):\n%s\n", test.enabled, diff)
+		}
+	}
+}
+
+func TestJUnitWriterDeeplyNestedSubtests(t *testing.T) {
+	const depth = 1000
+
+	var input strings.Builder
+	var names []string
+	name := "TestDeep"
+	for i := 0; i < depth; i++ {
+		names = append(names, name)
+		fmt.Fprintf(&input, `{"Action":"run","Package":"package/name","Test":%q}`+"\n", name)
+		name += "/x"
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		fmt.Fprintf(&input, `{"Action":"output","Package":"package/name","Test":%q,"Output":"--- PASS: %s (0.00s)\n"}`+"\n", names[i], names[i])
+		fmt.Fprintf(&input, `{"Action":"pass","Package":"package/name","Test":%q,"Elapsed":0}`+"\n", names[i])
+	}
+	fmt.Fprintf(&input, `{"Action":"pass","Package":"package/name","Elapsed":0}`+"\n")
+
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(report.Packages[0].Tests); got != depth {
+		t.Fatalf("unexpected number of tests, got %d, want %d", got, depth)
+	}
+
+	for _, jw := range []JUnitWriter{{}, {NestSubtests: true}, {SubtestSeparator: " › ", IncludePassingOutput: true}} {
+		var buf bytes.Buffer
+		if err := jw.Write(&buf, report); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		// The size of the report should be proportional to the size of the
+		// test names and output, regardless of how deep the tests are nested.
+		if max := 8 * input.Len(); buf.Len() > max {
+			t.Errorf("%+v: report of %d bytes exceeds %d bytes", jw, buf.Len(), max)
+		}
+		if err := xml.Unmarshal(buf.Bytes(), new(xmlTestsuites)); err != nil {
+			t.Errorf("%+v: invalid XML written: %v", jw, err)
+		}
+	}
+}
+
+func TestJUnitWriterConvert(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestOne"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"--- PASS: TestOne (0.00s)\n"}
+{"Action":"pass","Package":"package/name","Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":"package/name","Output":"PASS\n"}
+{"Action":"pass","Package":"package/name","Elapsed":0.01}
+`
+	var out, echo bytes.Buffer
+	if err := (JUnitWriter{}).Convert(strings.NewReader(input), &out, Echo(&echo)); err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+
+	wantEcho := "=== RUN   TestOne\n--- PASS: TestOne (0.00s)\nPASS\n"
+	if diff := cmp.Diff(wantEcho, echo.String()); diff != "" {
+		t.Errorf("unexpected echoed output, diff (-want, +got):\n%s\n", diff)
+	}
+
+	var suites xmlTestsuites
+	if err := xml.Unmarshal(out.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, out.String())
+	}
+	if strings.Contains(out.String(), "=== RUN") {
+		t.Errorf("echoed output was written to the report:\n%s", out.String())
+	}
+	if suites.Tests != 1 {
+		t.Errorf("unexpected number of tests, got %d, want 1", suites.Tests)
+	}
+}
+
+func TestJUnitWriterCDATA(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestFail", Result: FAIL, Output: []string{"got <a href=\"x\">&amp;</a>\n", "end ]]> of section\x00\n"}},
+			{Name: "TestPass", Result: PASS, Output: []string{"if a < b && c > d\n"}},
+		},
+	}}}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{CDATA: true, IncludePassingOutput: true}).Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<![CDATA[if a < b && c > d\n]]>") {
+		t.Errorf("output was not written as CDATA:\n%s", buf.String())
+	}
+
+	var suites xmlTestsuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, buf.String())
+	}
+	testcases := suites.Suites[0].Testcases
+	want := "got <a href=\"x\">&amp;</a>\nend ]]> of section�\n"
+	if got := testcases[0].Failure.Data; got != want {
+		t.Errorf("unexpected failure output, got %q, want %q", got, want)
+	}
+	if got, want := testcases[1].SystemOut.Data, "if a < b && c > d\n"; got != want {
+		t.Errorf("unexpected system-out, got %q, want %q", got, want)
+	}
+}
+
+func TestJUnitWriterAssertions(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestFail", Result: FAIL, Assertions: 3},
+			{Name: "TestPass", Result: PASS},
+		},
+	}}}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{}).Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if got := strings.Count(buf.String(), "assertions="); got != 1 {
+		t.Errorf("unexpected number of assertions attributes, got %d, want 1:\n%s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), `<testcase name="TestFail" classname="package/name" time="0.000" assertions="3">`) {
+		t.Errorf("assertions attribute missing for TestFail:\n%s", buf.String())
+	}
+}
+
+func TestJUnitWriterPackageProperty(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name:  "github.com/org/repo/service/foo",
+		Tests: []*Test{{Name: "TestOne", Result: PASS}},
+	}}}
+
+	jw := JUnitWriter{ModulePath: "github.com/org/repo", Surefire: true, PackageProperty: true}
+	tc := jw.testsuites(report).Suites[0].Testcases[0]
+	if got, want := tc.Classname, "service.foo"; got != want {
+		t.Errorf("unexpected classname, got %q, want %q", got, want)
+	}
+	want := &[]xmlProperty{{Name: "package", Value: "github.com/org/repo/service/foo"}}
+	if diff := cmp.Diff(want, tc.Properties); diff != "" {
+		t.Errorf("unexpected properties, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestJUnitWriterCommand(t *testing.T) {
+	report := &Report{Packages: []*Package{{Name: "package/name", CoveragePct: "50.0"}}}
+
+	tests := []struct {
+		command string
+		want    *[]xmlProperty
+	}{
+		{"", &[]xmlProperty{{Name: "coverage.statements.pct", Value: "50.0"}}},
+		{"go test -json -race ./...", &[]xmlProperty{
+			{Name: "coverage.statements.pct", Value: "50.0"},
+			{Name: "go.command", Value: "go test -json -race ./..."},
+		}},
+	}
+
+	for _, test := range tests {
+		got := (JUnitWriter{Command: test.command}).testsuites(report).Suites[0].Properties
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("Command=%q: unexpected testsuite properties, diff (-want, +got):\n%s\n", test.command, diff)
+		}
+	}
+}
+
+func TestJUnitWriterDurationRounding(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name:     "package/name",
+		Duration: 1234567 * time.Microsecond,
+		Tests: []*Test{
+			{Name: "TestOne", Result: PASS, Duration: 1499999 * time.Nanosecond},
+			{Name: "TestTwo", Result: PASS, Duration: 1234567 * time.Nanosecond},
+		},
+	}}}
+
+	tests := []struct {
+		rounding time.Duration
+		want     []string // times of the testsuite and its testcases
+	}{
+		{0, []string{"1.235", "0.001", "0.001"}},
+		{time.Millisecond, []string{"1.235", "0.001", "0.001"}},
+		{10 * time.Millisecond, []string{"1.230", "0.000", "0.000"}},
+		{time.Microsecond, []string{"1.234567", "0.001500", "0.001235"}},
+	}
+	for _, test := range tests {
+		suite := (JUnitWriter{DurationRounding: test.rounding}).testsuites(report).Suites[0]
+		got := []string{suite.Time}
+		for _, tc := range suite.Testcases {
+			got = append(got, tc.Time)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("DurationRounding=%v: unexpected times, diff (-want, +got):\n%s\n", test.rounding, diff)
+		}
+	}
+	if got, want := report.Packages[0].Tests[0].Duration, 1499999*time.Nanosecond; got != want {
+		t.Errorf("DurationRounding modified the report, got duration %v, want %v", got, want)
+	}
+}
+
+func TestJUnitWriterRetries(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{
+			{Name: "TestFlaky", Result: PASS, Attempts: []Result{FAIL, FAIL, PASS}},
+			{Name: "TestOnce", Result: FAIL, Attempts: []Result{FAIL}},
+			{Name: "TestUntracked", Result: PASS},
+		},
+	}}}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{}).Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if got := strings.Count(buf.String(), "retries="); got != 1 {
+		t.Errorf("unexpected number of retries attributes, got %d, want 1:\n%s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), `<testcase name="TestFlaky" classname="package/name" time="0.000" retries="2"></testcase>`) {
+		t.Errorf("retries attribute missing for passing TestFlaky:\n%s", buf.String())
+	}
+}
+
+func TestJUnitWriterSeparateBenchmarks(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name:     "package/name",
+		Duration: time.Second,
+		Tests: []*Test{
+			{Name: "TestOne", Result: PASS},
+			{Name: "BenchmarkOne", Result: PASS, Duration: 200 * time.Millisecond},
+			{Name: "BenchmarkTwo", Result: FAIL, Duration: 300 * time.Millisecond},
+		},
+	}}}
+
+	type suite struct {
+		Name, Time string
+		Tests      []string
+	}
+	tests := []struct {
+		separate bool
+		want     []suite
+	}{
+		{false, []suite{{"package/name", "1.000", []string{"TestOne", "BenchmarkOne", "BenchmarkTwo"}}}},
+		{true, []suite{
+			{"package/name", "1.000", []string{"TestOne"}},
+			{"package/name [bench]", "0.500", []string{"BenchmarkOne", "BenchmarkTwo"}},
+		}},
+	}
+	for _, test := range tests {
+		suites := (JUnitWriter{SeparateBenchmarks: test.separate}).testsuites(report)
+		var got []suite
+		for _, s := range suites.Suites {
+			var names []string
+			for _, tc := range s.Testcases {
+				names = append(names, tc.Name)
+			}
+			got = append(got, suite{s.Name, s.Time, names})
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("SeparateBenchmarks=%v: unexpected testsuites, diff (-want, +got):\n%s\n", test.separate, diff)
+		}
+		if suites.Tests != 3 || suites.Failures != 1 {
+			t.Errorf("SeparateBenchmarks=%v: unexpected totals, got tests=%d failures=%d, want tests=3 failures=1", test.separate, suites.Tests, suites.Failures)
+		}
+	}
+}
+
+func TestJUnitWriterDedent(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name: "package/name",
+		Tests: []*Test{{
+			Name:   "TestParent/child",
+			Result: FAIL,
+			Output: []string{
+				"        main_test.go:10: unexpected result\n",
+				"            got:  1\n",
+				"\n",
+				"            want: 2\n",
+				"    --- FAIL: TestParent/child (0.00s)\n",
+			},
+		}},
+	}}}
+
+	tests := []struct {
+		dedent bool
+		want   string
+	}{
+		{false, strings.Join(report.Packages[0].Tests[0].Output, "")},
+		{true, "    main_test.go:10: unexpected result\n        got:  1\n\n        want: 2\n--- FAIL: TestParent/child (0.00s)\n"},
+	}
+	for _, test := range tests {
+		tc := (JUnitWriter{Dedent: test.dedent}).testsuites(report).Suites[0].Testcases[0]
+		if diff := cmp.Diff(test.want, tc.Failure.Data); diff != "" {
+			t.Errorf("Dedent=%v: unexpected failure, diff (-want, +got):\n%s\n", test.dedent, diff)
+		}
+	}
+}
+
+func TestDottedClassname(t *testing.T) {
+	tests := map[string]string{
+		"github.com/org/repo/pkg":  "github.com.org.repo.pkg",
+		"github.com/org/repo/pkg/": "github.com.org.repo.pkg",
+		"/abs//path":               "abs.path",
+		"main":                     "main",
+		"":                         "",
+	}
+	for pkg, want := range tests {
+		if got := DottedClassname(pkg); got != want {
+			t.Errorf("DottedClassname(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+
+	report := &Report{Packages: []*Package{{
+		Name:  "github.com/org/repo/service/foo",
+		Tests: []*Test{{Name: "TestOne", Result: PASS}},
+	}}}
+	jw := JUnitWriter{ModulePath: "github.com/org/repo", Classname: DottedClassname}
+	suite := jw.testsuites(report).Suites[0]
+	if got, want := suite.Testcases[0].Classname, "service.foo"; got != want {
+		t.Errorf("unexpected classname, got %q, want %q", got, want)
+	}
+	if got, want := suite.Name, "service/foo"; got != want {
+		t.Errorf("unexpected testsuite name, got %q, want %q", got, want)
+	}
+}
+
+func TestJUnitWriterTestifySuites(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestMySuite"}
+{"Action":"run","Package":"package/name","Test":"TestMySuite/TestCreate"}
+{"Action":"pass","Package":"package/name","Test":"TestMySuite/TestCreate","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestMySuite/TestDelete"}
+{"Action":"run","Package":"package/name","Test":"TestMySuite/TestDelete/missing"}
+{"Action":"pass","Package":"package/name","Test":"TestMySuite/TestDelete/missing","Elapsed":0}
+{"Action":"pass","Package":"package/name","Test":"TestMySuite/TestDelete","Elapsed":0}
+{"Action":"pass","Package":"package/name","Test":"TestMySuite","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestTable"}
+{"Action":"run","Package":"package/name","Test":"TestTable/empty"}
+{"Action":"pass","Package":"package/name","Test":"TestTable/empty","Elapsed":0}
+{"Action":"pass","Package":"package/name","Test":"TestTable","Elapsed":0}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testcase struct{ Classname, Name string }
+	tests := []struct {
+		testify bool
+		want    []testcase
+	}{
+		{false, []testcase{
+			{"package/name", "TestMySuite/TestCreate"},
+			{"package/name", "TestMySuite/TestDelete/missing"},
+			{"package/name", "TestMySuite/TestDelete"},
+			{"package/name", "TestMySuite"},
+			{"package/name", "TestTable/empty"},
+			{"package/name", "TestTable"},
+		}},
+		{true, []testcase{
+			{"TestMySuite", "TestCreate"},
+			{"TestMySuite", "TestDelete/missing"},
+			{"TestMySuite", "TestDelete"},
+			{"package/name", "TestMySuite"},
+			{"package/name", "TestTable/empty"},
+			{"package/name", "TestTable"},
+		}},
+	}
+	for _, test := range tests {
+		var got []testcase
+		for _, tc := range (JUnitWriter{TestifySuites: test.testify}).testsuites(report).Suites[0].Testcases {
+			got = append(got, testcase{tc.Classname, tc.Name})
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("TestifySuites=%v: unexpected testcases, diff (-want, +got):\n%s\n", test.testify, diff)
+		}
+	}
+}
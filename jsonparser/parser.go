@@ -2,13 +2,54 @@ package jsonparser
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// regexSummary matches the package summary line, e.g. "ok  pkg  1.234s" or
+// "ok  pkg  (cached)", and captures the package name, duration and cached
+// indicator.
+var regexSummary = regexp.MustCompile(`^(?:ok|FAIL)\s+(\S+)\s+(?:(\d+\.\d+)s|(\(cached\)))`)
+
+// regexNoTestFiles matches the summary line of a package without test files,
+// e.g. "?   pkg  [no test files]", and captures the package name.
+var regexNoTestFiles = regexp.MustCompile(`^\?\s+(\S+)\s+\[no test files\]$`)
+
+// regexCoverage matches the coverage percentage printed by go test -cover,
+// e.g. "coverage: 61.2% of statements". When -coverpkg is used the line ends
+// with the packages the coverage was measured for, e.g. "in ./...".
+var regexCoverage = regexp.MustCompile(`coverage: (\d+(?:\.\d+)?)% of statements(?: in \S+)?`)
+
+// regexBenchmarkResult matches the result line of a benchmark, e.g.
+// "BenchmarkFoo-8  1000  1234 ns/op  16 B/op  1 allocs/op", and captures the
+// benchmark name, ns/op, B/op (optional) and allocs/op (optional).
+var regexBenchmarkResult = regexp.MustCompile(`^(Benchmark\S*?)(?:-\d+)?\s+\d+\s+(\d+(?:\.\d+)?) ns/op(?:\s+\d+(?:\.\d+)? MB/s)?(?:\s+(\d+) B/op)?(?:\s+(\d+) allocs/op)?`)
+
+// regexBenchmarkStatus matches the status line of a benchmark that was
+// skipped or failed, or that passed and printed log output, e.g.
+// "--- SKIP: BenchmarkFoo", and captures the status and benchmark name.
+var regexBenchmarkStatus = regexp.MustCompile(`^--- (BENCH|FAIL|SKIP): (Benchmark\S*?)(?:-\d+)?$`)
+
+// utf8BOM is the UTF-8 encoded byte order mark.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// regexTestFilePath matches a path of a _test.go file followed by a line
+// number, e.g. "/src/foo/foo_test.go:12", and captures its directory.
+var regexTestFilePath = regexp.MustCompile(`(\S*)/[^/\s]+_test\.go:\d+`)
+
+// regexFuzzCorpus matches the line printed by go test when a fuzz test
+// failed and the failing input was written to the corpus, and captures the
+// path of the written file.
+var regexFuzzCorpus = regexp.MustCompile(`^\s*Failing input written to (\S+)`)
+
 // Result represents a test result.
 type Result int
 
@@ -22,6 +63,14 @@ const (
 // Report is a collection of package tests.
 type Report struct {
 	Packages []*Package
+
+	// RaceEnabled is set when the output of any package indicates that the
+	// tests were run with the race detector enabled, see Package.DataRace.
+	RaceEnabled bool
+
+	// Timeout is the value of the -timeout flag that go test was run with, if
+	// it was given to the parser using the Timeout option.
+	Timeout time.Duration
 }
 
 // Package contains the test results of a single package.
@@ -32,8 +81,72 @@ type Package struct {
 	Benchmarks  []*Benchmark
 	CoveragePct string
 
-	// Time is deprecated, use Duration instead.
-	Time int // in milliseconds
+	// Cached is set when the results of this package were served from the
+	// go test cache.
+	Cached bool
+
+	// DataRace is set when the output of this package indicates that the
+	// race detector was enabled, i.e. when it contains a "WARNING: DATA
+	// RACE" report or mentions the -test.race flag. Go does not report
+	// whether the race detector was enabled, so packages that were run with
+	// it but did not contain any races cannot be detected.
+	DataRace bool
+
+	// NoTestFiles is set when go test reported "[no test files]" for this
+	// package.
+	NoTestFiles bool
+
+	// NoTestsToRun is set when go test printed "testing: warning: no tests
+	// to run" for this package, which happens when the -run flag did not
+	// match any tests. Such packages pass without having any tests.
+	NoTestsToRun bool
+
+	// Timestamp is the time of the first event seen for this package.
+	Timestamp time.Time
+
+	// Warnings contains problems with the input that were found while
+	// parsing this package and did not prevent it from being parsed, e.g.
+	// invalid elapsed times. Affected durations are set to 0.
+	Warnings []string
+
+	// MaxParallel is the maximum number of tests of this package that were
+	// running at the same time, based on their Start and End times. Tests
+	// with subtests are not counted, since they are running for as long as
+	// any of their subtests are. Tests without a Start or End time are
+	// ignored, so MaxParallel is 0 if no times were reported.
+	MaxParallel int
+
+	// Errors contains the failures of this package that do not belong to
+	// any of its tests, such as a TestMain that failed before or after
+	// running the tests.
+	Errors []PackageError
+
+	// Time is deprecated, use Duration instead. It is left out when
+	// encoding to JSON, where Duration is encoded in nanoseconds.
+	Time int `json:"-"` // in milliseconds
+}
+
+// PackageError is a failure of a package outside of its tests. A package
+// that failed while none of its tests did is assumed to have failed in the
+// setup phase if it did not run any tests, and in the teardown phase
+// otherwise.
+type PackageError struct {
+	Phase string // "setup" or "teardown"
+
+	// Output is the output of the package that does not belong to any
+	// test, without status and summary lines. Like Test.Output it is
+	// rewritten by the OutputRewriter option.
+	Output []string
+}
+
+// NewPackage returns a new Package with the given name and no tests or
+// benchmarks.
+func NewPackage(name string) *Package {
+	return &Package{
+		Name:       name,
+		Tests:      make([]*Test, 0),
+		Benchmarks: make([]*Benchmark, 0),
+	}
 }
 
 // Test contains the results of a single test.
@@ -44,10 +157,111 @@ type Test struct {
 	Result   Result
 	Output   []string
 
+	// Incomplete is set for tests that never received a pass, fail or skip
+	// action, e.g. because go test was killed while the test was running.
+	// It is only set when the parser was created with the MarkIncomplete
+	// option. Incomplete tests keep their FAIL result.
+	Incomplete bool
+
+	// Orphaned is set for subtests that never received a pass, fail or skip
+	// action while their parent test passed or was skipped, e.g. because
+	// the parent returned before the subtest finished. Orphaned subtests are
+	// marked as Incomplete, or as skipped when the parser was created with
+	// the SkipOrphanedSubtests option, instead of keeping their FAIL result.
+	Orphaned bool
+
+	// Fatal is set for failed tests that appear to have been stopped by a
+	// call to t.Fatal or t.FailNow rather than having accumulated errors.
+	// This is a best-effort heuristic: go test does not report this
+	// distinction, so a test is considered fatal when the last line of its
+	// output before the "--- FAIL:" line mentions "FATAL" or "Fatal".
+	Fatal bool
+
+	// Timestamp is the time at which the test started running.
+	//
+	// Deprecated: use Start instead.
+	Timestamp time.Time
+
+	// Start is the time at which the test started running, and End the time
+	// at which it finished. Start is only known if the test output contained
+	// a run action for this test. End is the time of the pass, fail or skip
+	// action of the test. If that action has no time, End is derived from
+	// Start and Duration.
+	Start time.Time
+	End   time.Time
+
+	// Events contains the scheduling markers found in the test output, in
+	// the order they were seen. It is only populated when the parser was
+	// created with the TrackEvents option.
+	Events []TestEvent
+
+	// LeakDetected is set for tests whose output contains a goroutine leak
+	// report, as printed by leak detectors such as go.uber.org/goleak when
+	// "found unexpected goroutines". Such tests are reported as errored
+	// rather than failed, and the leak report remains part of Output.
+	LeakDetected bool
+
+	// Assertions is the number of failed assertions of a failed test. It is
+	// only set when the parser was created with the InferAssertions option.
+	// This is a best-effort estimate: every line of output that starts with
+	// a file and line location, as added by t.Error, t.Fatal and t.Log, is
+	// counted as one failed assertion.
+	Assertions int
+
+	// LikelyTimedOut is set for tests that were probably stopped because
+	// go test reached its -timeout. It is only set when the parser was
+	// created with the Timeout option, for tests that ran for at least 90%
+	// of the timeout and for tests that did not complete in a package that
+	// ran for at least 90% of the timeout.
+	LikelyTimedOut bool
+
+	// Owner is the owner of the test, as printed by the test on a line that
+	// starts with the marker given to the OwnerMarker option. If the marker
+	// was printed multiple times, the last owner is used.
+	Owner string
+
+	// FuzzCorpus is the path of the file containing the failing input of a
+	// fuzz test, as reported by go test in the "Failing input written to"
+	// line of the test output.
+	FuzzCorpus string
+
+	// Stderr contains the output this test wrote to stderr, if it was
+	// parsed separately using Parser.ParseWithStderr. Otherwise output
+	// written to stderr is part of Output. JUnitWriter writes it to the
+	// system-err element of the testcase.
+	Stderr []string
+
+	// Attempts contains the result of every run of this test in the order
+	// they completed, e.g. when go test was run with -count or when a test
+	// was retried in the same output. It is only populated when the parser
+	// was created with the TrackAttempts option. Result is the result of the
+	// last attempt.
+	Attempts []Result
+
 	SubtestIndent string
 
-	// Time is deprecated, use Duration instead.
-	Time int // in milliseconds
+	// Time is deprecated, use Duration instead. It is left out when
+	// encoding to JSON, where Duration is encoded in nanoseconds.
+	Time int `json:"-"` // in milliseconds
+}
+
+// TestEvent is a scheduling marker of a test, as printed by go test in lines
+// like "=== RUN   TestName", "=== PAUSE TestName" or "=== CONT  TestName".
+type TestEvent struct {
+	Action string // "run", "pause" or "cont"
+	Time   time.Time
+}
+
+// NewTest returns a new Test with the given name in package pkg. The result
+// of the new test is PASS. Note that this differs from tests created by the
+// parser, which start out as FAIL until a pass or skip action is seen.
+func NewTest(name, pkg string) *Test {
+	return &Test{
+		Name:    name,
+		Package: pkg,
+		Result:  PASS,
+		Output:  make([]string, 0),
+	}
 }
 
 // Benchmark contains the results of a single benchmark.
@@ -58,124 +272,680 @@ type Benchmark struct {
 	Bytes int
 	// number of allocs/op
 	Allocs int
+
+	// Result is FAIL for benchmarks that failed, e.g. because they called
+	// b.Fatal or panicked, and SKIP for benchmarks that were skipped. A
+	// benchmark that failed before reporting any results has no Duration.
+	Result Result
+
+	// Skipped is true for benchmarks that were skipped, i.e. when Result is
+	// SKIP.
+	Skipped bool
 }
 
+// LineOutput is a single event in the go test -json output.
 type LineOutput struct {
 	Time    time.Time
 	Action  string
 	Package string
 	Test    string
-	Output  string
 	Elapsed float32
+
+	// Output contains a chunk of output including its trailing newline, if
+	// any. Blank lines are represented by "\n" rather than an empty string,
+	// and are therefore preserved in Test.Output like any other line.
+	Output string
+}
+
+// UnmarshalJSON decodes an event. Besides the number of seconds written by go
+// test, Elapsed may be given as a duration string, e.g. "1.23s", as written
+// by some other tools. An Elapsed string that cannot be parsed is decoded as
+// NaN, which the parser reports as an invalid elapsed time.
+func (l *LineOutput) UnmarshalJSON(data []byte) error {
+	type lineOutput LineOutput
+	aux := struct {
+		*lineOutput
+		Elapsed json.RawMessage
+	}{lineOutput: (*lineOutput)(l)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	l.Elapsed = 0
+	if len(aux.Elapsed) == 0 || string(aux.Elapsed) == "null" {
+		return nil
+	}
+	if aux.Elapsed[0] != '"' {
+		return json.Unmarshal(aux.Elapsed, &l.Elapsed)
+	}
+	var s string
+	if err := json.Unmarshal(aux.Elapsed, &s); err != nil {
+		return err
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		l.Elapsed = float32(d.Seconds())
+	} else {
+		l.Elapsed = float32(math.NaN())
+	}
+	return nil
+}
+
+// Option defines options that can be passed to NewParser.
+type Option func(*Parser)
+
+// PackageName is an Option that sets the default package name to use when it
+// cannot be determined from the test output.
+func PackageName(name string) Option {
+	return func(p *Parser) {
+		p.packageName = name
+	}
+}
+
+// InferPackageName is an Option that derives the name of the package from
+// the test output when events have no package. The name is the common
+// directory of the _test.go file paths in the output of failed tests, as
+// found in panic stack traces. This is a heuristic: the result is a directory
+// rather than an import path, and it is only available if the output
+// contains full paths. If no name can be inferred, the name set by the
+// PackageName option is used.
+func InferPackageName(enabled bool) Option {
+	return func(p *Parser) {
+		p.inferPackageName = enabled
+	}
+}
+
+// ProgressWriter is an Option that sets a writer to which a live tally of
+// completed tests is written while parsing. A single character is written as
+// soon as a test completes: "." for a passing test, "F" for a failing test and
+// "s" for a skipped test.
+func ProgressWriter(w io.Writer) Option {
+	return func(p *Parser) {
+		p.progress = w
+	}
+}
+
+// MarkIncomplete is an Option that marks tests that never received a
+// terminal action as Incomplete. This typically happens when the go test
+// process is killed before all tests have finished.
+func MarkIncomplete(enabled bool) Option {
+	return func(p *Parser) {
+		p.markIncomplete = enabled
+	}
+}
+
+// SkipOrphanedSubtests is an Option that gives orphaned subtests, see
+// Test.Orphaned, the SKIP result. By default they are marked as Incomplete.
+func SkipOrphanedSubtests(enabled bool) Option {
+	return func(p *Parser) {
+		p.skipOrphanedSubtests = enabled
+	}
+}
+
+// MaxPackages is an Option that limits the number of packages the parser
+// accepts. Parsing fails with an error as soon as more than n packages are
+// found. A limit of 0 means no limit, which is the default.
+func MaxPackages(n int) Option {
+	return func(p *Parser) {
+		p.maxPackages = n
+	}
+}
+
+// MaxTests is an Option that limits the number of tests the parser accepts.
+// Parsing fails with an error as soon as more than n tests are found. A limit
+// of 0 means no limit, which is the default.
+func MaxTests(n int) Option {
+	return func(p *Parser) {
+		p.maxTests = n
+	}
+}
+
+// StrictActions is an Option that causes parsing to fail when an event with
+// an action not known to this parser is found. By default unknown actions
+// are ignored.
+func StrictActions(enabled bool) Option {
+	return func(p *Parser) {
+		p.strictActions = enabled
+	}
+}
+
+// TrackEvents is an Option that records the "=== RUN", "=== PAUSE" and
+// "=== CONT" markers in the output of each test as Test.Events. This can be
+// used to reconstruct a timeline of parallel tests.
+func TrackEvents(enabled bool) Option {
+	return func(p *Parser) {
+		p.trackEvents = enabled
+	}
+}
+
+// StripSchedulingMarkers is an Option that leaves the "=== RUN",
+// "=== PAUSE", "=== CONT" and "=== NAME" lines out of Test.Output, which
+// rarely add anything to the output of a failed test. By default these lines
+// are kept. The TrackEvents option still sees the stripped lines.
+func StripSchedulingMarkers(enabled bool) Option {
+	return func(p *Parser) {
+		p.stripSchedulingMarkers = enabled
+	}
+}
+
+// TrackAttempts is an Option that records the result of every run of a test
+// as Test.Attempts. By default only the result of the last run is kept.
+func TrackAttempts(enabled bool) Option {
+	return func(p *Parser) {
+		p.trackAttempts = enabled
+	}
+}
+
+// PropagateFailures is an Option that controls whether tests are marked as
+// failed when any of their subtests failed, even if go test reported the test
+// itself as passed or skipped. This is enabled by default.
+func PropagateFailures(enabled bool) Option {
+	return func(p *Parser) {
+		p.propagateFailures = enabled
+	}
+}
+
+// DefaultMaxLineBytes is the default maximum length of a line of input, see
+// MaxLineBytes.
+const DefaultMaxLineBytes = 1024 * 1024
+
+// MaxLineBytes is an Option that sets the maximum length in bytes of a single
+// line of input, which defaults to DefaultMaxLineBytes. Parsing fails with an
+// error when a longer line is found. The buffer used to read lines grows as
+// needed up to this size, so a higher limit only uses more memory for inputs
+// that actually contain long lines, e.g. tests that log large values in a
+// single call. A limit of zero or less restores DefaultMaxLineBytes.
+func MaxLineBytes(n int) Option {
+	return func(p *Parser) {
+		if n <= 0 {
+			n = DefaultMaxLineBytes
+		}
+		p.maxLineBytes = n
+	}
+}
+
+// Echo is an Option that sets the writer to which the output of all tests is
+// copied while parsing. By default output is copied to os.Stderr, so that it
+// does not mix with a report written to os.Stdout. Use Echo(nil) to disable
+// copying output.
+func Echo(w io.Writer) Option {
+	return func(p *Parser) {
+		p.echo = w
+	}
+}
+
+// InferAssertions is an Option that sets Test.Assertions for failed tests to
+// the number of assertions that appear to have failed, based on their output.
+func InferAssertions(enabled bool) Option {
+	return func(p *Parser) {
+		p.inferAssertions = enabled
+	}
+}
+
+// EchoLines is an Option that only echoes complete lines of output. By
+// default output is echoed in the chunks it was reported in, which may be
+// partial lines. Partial lines of different tests that run in parallel are
+// then written next to each other. With EchoLines enabled, partial lines are
+// buffered per test until the rest of the line has been read, or until the
+// end of the input.
+func EchoLines(enabled bool) Option {
+	return func(p *Parser) {
+		p.echoLines = enabled
+	}
+}
+
+// OwnerMarker is an Option that sets Test.Owner from lines in the output of a
+// test that start with the given marker, e.g. with marker "OWNER:" the line
+// "    main_test.go:10: OWNER: team-foo" sets the owner to "team-foo". The file
+// and line location added by t.Log are ignored. By default no owners are
+// extracted.
+func OwnerMarker(marker string) Option {
+	return func(p *Parser) {
+		p.ownerMarker = marker
+	}
+}
+
+// OutputRewriter is an Option that sets a function that is called with every
+// chunk of test output before it is added to Test.Output, and whose result is
+// stored instead. Output for which fn returns an empty string is dropped.
+// This can be used to redact secrets from the report. Echoed output is not
+// rewritten.
+func OutputRewriter(fn func(string) string) Option {
+	return func(p *Parser) {
+		p.outputRewriter = fn
+	}
+}
+
+// EchoFailuresOnly is an Option that only echoes the output of tests that
+// failed. The output of every test is buffered until the test completes, and
+// is only echoed if the test failed or never completed. Output that does not
+// belong to a test is always echoed.
+func EchoFailuresOnly(enabled bool) Option {
+	return func(p *Parser) {
+		p.echoFailuresOnly = enabled
+	}
+}
+
+// Timeout is an Option that sets the value of the -timeout flag go test was
+// run with. It is stored in Report.Timeout and used to mark tests as
+// LikelyTimedOut. The timeout is not part of the go test output, so it cannot
+// be determined by the parser itself.
+func Timeout(d time.Duration) Option {
+	return func(p *Parser) {
+		p.timeout = d
+	}
+}
+
+// Parser is a go test json output parser. Events are processed as soon as
+// they are read, which allows progress to be reported while tests are still
+// running.
+//
+// A Parser has no state of its own while parsing, so it is safe to call
+// Parse and ParseStream concurrently on independent readers, using the same
+// or different parsers. The progress and echo writers are shared by all
+// these calls, however, and must be safe for concurrent use. Concurrent
+// writes to the default echo writer os.Stderr do not race, but the output of
+// different calls may be interleaved; use the Echo option to give every call
+// its own writer.
+type Parser struct {
+	packageName            string
+	progress               io.Writer
+	markIncomplete         bool
+	maxPackages            int
+	maxTests               int
+	strictActions          bool
+	trackEvents            bool
+	timeout                time.Duration
+	echo                   io.Writer
+	echoLines              bool
+	echoFailuresOnly       bool
+	propagateFailures      bool
+	maxLineBytes           int
+	inferAssertions        bool
+	ownerMarker            string
+	outputRewriter         func(string) string
+	stripSchedulingMarkers bool
+	trackAttempts          bool
+	inferPackageName       bool
+	skipOrphanedSubtests   bool
+}
+
+// NewParser returns a new go test json output parser.
+func NewParser(options ...Option) *Parser {
+	p := &Parser{
+		echo:              os.Stderr,
+		propagateFailures: true,
+		maxLineBytes:      DefaultMaxLineBytes,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
 }
 
 // Parse parses go test output from reader r and returns a report with the
 // results. An optional pkgName can be given, which is used in case a package
 // result line is missing.
 func Parse(r io.Reader, pkgName string) (*Report, error) {
-	reader := bufio.NewReader(r)
+	return NewParser(PackageName(pkgName)).Parse(r)
+}
 
-	report := &Report{make([]*Package, 0)}
+// Parse parses go test output from reader r and returns a report with the
+// results.
+func (p *Parser) Parse(r io.Reader) (*Report, error) {
+	report := &Report{Packages: make([]*Package, 0), Timeout: p.timeout}
+	err := p.ParseStream(r, func(pkg *Package) error {
+		report.Packages = append(report.Packages, pkg)
+		report.RaceEnabled = report.RaceEnabled || pkg.DataRace
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
 
-	// keep track of tests we find
-	var tests []*Test
+// ParseDir parses all files in directory dir whose name matches the
+// filepath.Match pattern glob, and merges their results into a single report
+// using Report.Merge. Files are parsed in lexical order. A failure to parse
+// one file does not prevent the others from being parsed; the errors of all
+// files are combined into the returned error, together with a report of the
+// files that were parsed successfully.
+func ParseDir(dir, glob string) (*Report, error) {
+	return NewParser().ParseDir(dir, glob)
+}
 
-	// keep track of benchmarks we find
-	var benchmarks []*Benchmark
+// ParseDir parses all files in directory dir whose name matches glob, see
+// ParseDir.
+func (p *Parser) ParseDir(dir, glob string) (*Report, error) {
+	files, err := filepath.Glob(filepath.Join(dir, glob))
+	if err != nil {
+		return nil, err
+	}
 
-	// coverage percentage report for current package
-	var coveragePct string
+	report := &Report{Packages: make([]*Package, 0), Timeout: p.timeout}
+	var errs multiError
+	for _, file := range files {
+		r, err := p.parseFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		report.Merge(r)
+	}
+	return report, errs.errorOrNil()
+}
 
-	// parse lines
-	for {
-		l, _, err := reader.ReadLine()
+func (p *Parser) parseFile(name string) (*Report, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return p.Parse(f)
+}
 
-		if err != nil && err == io.EOF {
-			break
-		} else if err != nil {
-			return nil, err
+// ParseStream parses go test output from reader r and calls fn for every
+// package as soon as its terminal action has been read. Packages that never
+// completed are passed to fn once the end of r has been reached. Only the
+// packages and tests that have not yet completed are kept in memory. If fn
+// returns an error, parsing stops and that error is returned.
+//
+// Besides the JSON Lines output of go test -json, r may contain a single JSON
+// array of events, as written by some tools that collect the events.
+func (p *Parser) ParseStream(r io.Reader, fn func(*Package) error) error {
+	state := newParseState(p)
+	echo := newEchoWriter(p.echo, p.echoLines, p.echoFailuresOnly)
+	defer echo.flush()
+
+	handle := func(lineoutput LineOutput) error {
+		echo.write(lineoutput)
+
+		pkg, err := state.handle(lineoutput)
+		if err != nil {
+			return err
 		}
+		if pkg != nil {
+			return fn(pkg)
+		}
+		return nil
+	}
 
-		var lineoutput LineOutput
-		json.Unmarshal(l, &lineoutput)
-
-		fmt.Fprintf(os.Stderr, "%s", lineoutput.Output)
-
-		if lineoutput.Test == "" {
-			var p *Package
-			if p = findPackage(report.Packages, lineoutput.Package); p == nil {
-				p = &Package{
-					Name:        lineoutput.Package,
-					Duration:    0,
-					Tests:       make([]*Test, 0),
-					Benchmarks:  benchmarks,
-					CoveragePct: coveragePct,
-				}
-				report.Packages = append(report.Packages, p)
-			}
+	if err := p.readEvents(r, handle); err != nil {
+		return err
+	}
 
-			if lineoutput.Action == "pass" {
-				p.Duration = time.Duration(lineoutput.Elapsed * float32(time.Second))
-			}
-		} else {
-			var t *Test
-			if t = findTest(tests, lineoutput.Test); t == nil {
-				t = &Test{
-					Name:    lineoutput.Test,
-					Package: lineoutput.Package,
-					Result:  FAIL,
-					Output:  make([]string, 0),
-				}
-				tests = append(tests, t)
+	for _, pkg := range state.flush() {
+		if err := fn(pkg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseWithStderr parses go test output like Parse, where the events read
+// from stderr contain the output that the tests wrote to stderr. This can be
+// used when the stdout and stderr of the test binary were converted to events
+// separately, e.g. by running go tool test2json on each of them. The output
+// events read from stderr are stored in Test.Stderr of the corresponding test,
+// subject to the same StripSchedulingMarkers and OutputRewriter options as
+// Test.Output. All other events in stderr are ignored. Output of tests that do
+// not appear in stdout is dropped.
+func (p *Parser) ParseWithStderr(stdout, stderr io.Reader) (*Report, error) {
+	output := make(map[string][]string)
+	err := p.readEvents(stderr, func(lineoutput LineOutput) error {
+		if lineoutput.Action == "output" && lineoutput.Test != "" {
+			if lineoutput.Package == "" {
+				lineoutput.Package = p.packageName
 			}
-			if lineoutput.Action == "output" {
-				t.Output = append(t.Output, lineoutput.Output)
+			if o := p.storedOutput(lineoutput.Output); o != "" {
+				key := lineoutput.Package + "\x00" + lineoutput.Test
+				output[key] = append(output[key], o)
 			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			if lineoutput.Action == "pass" || lineoutput.Action == "fail" {
-				if lineoutput.Action == "pass" {
-					t.Result = PASS
-				}
-				t.Duration = time.Duration(lineoutput.Elapsed * float32(time.Second))
-			}
+	report, err := p.Parse(stdout)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range report.Packages {
+		for _, t := range pkg.Tests {
+			t.Stderr = output[t.Package+"\x00"+t.Name]
 		}
 	}
+	return report, nil
+}
 
-	for _, t := range tests {
-		var p *Package
-		if p = findPackage(report.Packages, t.Package); p == nil {
-			p = &Package{
-				Name:        t.Package,
-				Duration:    0,
-				Tests:       make([]*Test, 0),
-				Benchmarks:  benchmarks,
-				CoveragePct: coveragePct,
-			}
-			report.Packages = append(report.Packages, p)
+// readEvents reads events from r, which contains either JSON Lines or a JSON
+// array of events, and calls handle for every event.
+func (p *Parser) readEvents(r io.Reader, handle func(LineOutput) error) error {
+	br := bufio.NewReader(r)
+	if isJSONArray(br) {
+		return p.parseArray(br, handle)
+	}
+	return p.parseLines(br, handle)
+}
+
+// parseLines reads events from r, one per line, and calls handle for every
+// event. Lines that are not a valid event are ignored.
+func (p *Parser) parseLines(r io.Reader, handle func(LineOutput) error) error {
+	// The scanner never shrinks below the capacity of its initial buffer, so
+	// it must not be larger than the limit.
+	size := 64 * 1024
+	if p.maxLineBytes < size {
+		size = p.maxLineBytes
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, size), p.maxLineBytes)
+	for scanner.Scan() {
+		l := scanner.Bytes()
+
+		// Files written on Windows, e.g. by PowerShell's Out-File, may
+		// start with a byte order mark. When such files are concatenated
+		// it can also appear at the start of any other line.
+		l = bytes.TrimPrefix(l, utf8BOM)
+
+		// Only complete events are interpreted. Text that is not a valid
+		// event, either on its own line or inside the Output payload of an
+		// event, never creates tests or packages.
+		var lineoutput LineOutput
+		if err := json.Unmarshal(l, &lineoutput); err != nil || lineoutput.Action == "" {
+			continue
 		}
+		if err := handle(lineoutput); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err == bufio.ErrTooLong {
+		return fmt.Errorf("line exceeds the maximum of %d bytes, see MaxLineBytes", p.maxLineBytes)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
 
-		p.Tests = append(p.Tests, t)
+// parseArray reads a JSON array of events from r and calls handle for every
+// event. Unlike parseLines, the array must be valid JSON, otherwise an error
+// is returned. Elements without an action are ignored.
+func (p *Parser) parseArray(r io.Reader, handle func(LineOutput) error) error {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil { // opening bracket
+		return err
+	}
+	for dec.More() {
+		var lineoutput LineOutput
+		if err := dec.Decode(&lineoutput); err != nil {
+			return fmt.Errorf("invalid event in JSON array: %w", err)
+		}
+		if lineoutput.Action == "" {
+			continue
+		}
+		if err := handle(lineoutput); err != nil {
+			return err
+		}
 	}
+	_, err := dec.Token() // closing bracket
+	return err
+}
 
-	return report, nil
+// isJSONArray returns true if the first character in r, ignoring leading
+// whitespace and a byte order mark, opens a JSON array. The skipped
+// characters are consumed from r.
+func isJSONArray(r *bufio.Reader) bool {
+	if b, err := r.Peek(len(utf8BOM)); err == nil && bytes.Equal(b, utf8BOM) {
+		r.Discard(len(utf8BOM))
+	}
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.Discard(1)
+		case '[':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// storedOutput returns output as it is stored in the report, after applying
+// the StripSchedulingMarkers and OutputRewriter options. An empty string is
+// returned for output that should not be stored.
+func (p *Parser) storedOutput(output string) string {
+	if p.stripSchedulingMarkers && isSchedulingMarker(output) {
+		return ""
+	}
+	if rewrite := p.outputRewriter; rewrite != nil {
+		output = rewrite(output)
+	}
+	return output
+}
+
+// writeProgress writes the progress character for the given test result to
+// the progress writer, if one was configured.
+func (p *Parser) writeProgress(result Result) {
+	if p.progress == nil {
+		return
+	}
+	var c string
+	switch result {
+	case PASS:
+		c = "."
+	case FAIL:
+		c = "F"
+	case SKIP:
+		c = "s"
+	}
+	fmt.Fprint(p.progress, c) // ignore error, progress is best-effort
+}
+
+// parseSeconds parses a duration in seconds, e.g. "1.234". Invalid durations
+// are returned as 0.
+func parseSeconds(s string) time.Duration {
+	// ignore error
+	d, _ := time.ParseDuration(s + "s")
+	return d
 }
 
-func findTest(tests []*Test, name string) *Test {
-	for i := len(tests) - 1; i >= 0; i-- {
-		if tests[i].Name == name {
-			return tests[i]
+// isFatal returns true if the last meaningful line of output looks like it
+// was written by a fatal log call.
+func isFatal(output []string) bool {
+	for i := len(output) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(output[i])
+		if line == "" || strings.HasPrefix(line, "--- FAIL:") {
+			continue
 		}
+		return strings.Contains(line, "FATAL") || strings.Contains(line, "Fatal")
 	}
-	return nil
+	return false
+}
+
+// isRaceIndicator returns true if the given line of output indicates that the
+// race detector was enabled.
+func isRaceIndicator(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "WARNING: DATA RACE") || strings.Contains(line, "-test.race")
 }
 
-func findPackage(packages []*Package, name string) *Package {
-	for i := len(packages) - 1; i >= 0; i-- {
-		if packages[i].Name == name {
-			return packages[i]
+// isSchedulingMarker returns true if the given line of output is a scheduling
+// marker printed by go test, e.g. "=== RUN   TestName".
+func isSchedulingMarker(line string) bool {
+	for _, marker := range []string{"=== RUN ", "=== PAUSE ", "=== CONT ", "=== NAME "} {
+		if strings.HasPrefix(line, marker) {
+			return true
 		}
 	}
-	return nil
+	return false
+}
+
+// inferPackageName returns the common directory of the test file paths in
+// the output of the failed tests in tests, or an empty string if there is no
+// such directory.
+func inferPackageName(tests []*Test) string {
+	var common []string
+	found := false
+	for _, t := range tests {
+		if t.Result != FAIL {
+			continue
+		}
+		for _, line := range t.Output {
+			for _, m := range regexTestFilePath.FindAllStringSubmatch(line, -1) {
+				dir := strings.Split(m[1], "/")
+				if !found {
+					common, found = dir, true
+					continue
+				}
+				n := 0
+				for n < len(common) && n < len(dir) && common[n] == dir[n] {
+					n++
+				}
+				common = common[:n]
+			}
+		}
+	}
+	return strings.Join(common, "/")
+}
+
+// parseOwner returns the owner on the given line of output if it starts with
+// marker, optionally preceded by a file and line location.
+func parseOwner(marker, line string) (string, bool) {
+	line = regexLogLocation.ReplaceAllString(strings.TrimSpace(line), "")
+	if !strings.HasPrefix(line, marker) {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(marker):]), true
+}
+
+// countAssertions returns the number of lines in output that start with a
+// file and line location.
+func countAssertions(output []string) int {
+	n := 0
+	for _, line := range output {
+		if regexLocation.MatchString(strings.TrimSpace(line)) {
+			n++
+		}
+	}
+	return n
+}
+
+// parseResult returns the Result for the given terminal test action. The
+// bench action is used for benchmarks that passed and printed log output, as
+// reported by a "--- BENCH:" line.
+func parseResult(action string) Result {
+	switch action {
+	case "pass", "bench":
+		return PASS
+	case "skip":
+		return SKIP
+	default:
+		return FAIL
+	}
 }
 
 // Failures counts the number of failed tests in this report
@@ -0,0 +1,1638 @@
+package jsonparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProgressWriter(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestPass"}
+{"Action":"pass","Package":"package/name","Test":"TestPass","Elapsed":0.01}
+{"Action":"run","Package":"package/name","Test":"TestFail"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"--- FAIL: TestFail (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestFail","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestSkip"}
+{"Action":"skip","Package":"package/name","Test":"TestSkip","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestPassAgain"}
+{"Action":"pass","Package":"package/name","Test":"TestPassAgain","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0.02}
+`
+	var progress bytes.Buffer
+	if _, err := NewParser(ProgressWriter(&progress)).Parse(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := progress.String(), ".Fs."; got != want {
+		t.Errorf("unexpected progress output, got %q, want %q", got, want)
+	}
+}
+
+func TestParseIgnoresTextEvents(t *testing.T) {
+	input := `=== RUN   TestRaw
+--- FAIL: TestRaw (0.00s)
+{"Action":"run","Package":"package/name","Test":"TestOne"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"=== RUN   TestPhantom\n"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"    --- FAIL: TestPhantom (0.00s)\n"}
+{"Action":"pass","Package":"package/name","Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":"package/name","Output":"--- FAIL: TestPhantom (0.00s)\n"}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Packages) != 1 {
+		t.Fatalf("unexpected number of packages, got %d, want 1", len(report.Packages))
+	}
+	pkg := report.Packages[0]
+	if pkg.Name != "package/name" {
+		t.Errorf("unexpected package name, got %q, want %q", pkg.Name, "package/name")
+	}
+	if len(pkg.Tests) != 1 || pkg.Tests[0].Name != "TestOne" {
+		t.Fatalf("unexpected tests in package, got %v, want only TestOne", pkg.Tests)
+	}
+	if got := pkg.Tests[0].Result; got != PASS {
+		t.Errorf("unexpected result for TestOne, got %v, want %v", got, PASS)
+	}
+}
+
+func TestParseRunTimestamp(t *testing.T) {
+	input := `{"Time":"2022-01-01T10:00:00Z","Action":"run","Package":"package/name","Test":"TestNoOutput"}
+{"Time":"2022-01-01T10:00:01Z","Action":"fail","Package":"package/name","Test":"TestNoOutput","Elapsed":1}
+{"Time":"2022-01-01T10:00:01Z","Action":"fail","Package":"package/name","Elapsed":1}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Packages) != 1 || len(report.Packages[0].Tests) != 1 {
+		t.Fatalf("expected a single package with a single test, got %+v", report.Packages)
+	}
+	test := report.Packages[0].Tests[0]
+	if test.Result != FAIL {
+		t.Errorf("unexpected result, got %v, want %v", test.Result, FAIL)
+	}
+	if want := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC); !test.Timestamp.Equal(want) {
+		t.Errorf("unexpected test timestamp, got %v, want %v", test.Timestamp, want)
+	}
+}
+
+func TestParseTruncatedStream(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestDone"}
+{"Action":"fail","Package":"package/name","Test":"TestDone","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestKilled"}
+{"Action":"output","Package":"package/name","Test":"TestKilled","Output":"=== RUN   TestKilled\n"}
+`
+	for _, enabled := range []bool{false, true} {
+		report, err := NewParser(MarkIncomplete(enabled)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := report.Packages[0].Tests
+		if len(tests) != 2 {
+			t.Fatalf("unexpected number of tests, got %d, want 2", len(tests))
+		}
+		if tests[0].Incomplete {
+			t.Errorf("MarkIncomplete(%v): completed test %s marked as incomplete", enabled, tests[0].Name)
+		}
+		if tests[1].Incomplete != enabled {
+			t.Errorf("MarkIncomplete(%v): test %s has Incomplete=%v, want %v", enabled, tests[1].Name, tests[1].Incomplete, enabled)
+		}
+		if tests[1].Result != FAIL {
+			t.Errorf("MarkIncomplete(%v): unexpected result for %s, got %v, want %v", enabled, tests[1].Name, tests[1].Result, FAIL)
+		}
+	}
+}
+
+func TestNewPackageAndTest(t *testing.T) {
+	pkg := NewPackage("package/name")
+	if pkg.Name != "package/name" || pkg.Tests == nil || len(pkg.Tests) != 0 || pkg.Benchmarks == nil {
+		t.Errorf("NewPackage returned unexpected package: %+v", pkg)
+	}
+
+	test := NewTest("TestOne", pkg.Name)
+	if test.Name != "TestOne" || test.Package != "package/name" {
+		t.Errorf("NewTest returned unexpected name or package: %+v", test)
+	}
+	if test.Result != PASS {
+		t.Errorf("NewTest returned unexpected result, got %v, want %v", test.Result, PASS)
+	}
+	if test.Output == nil || len(test.Output) != 0 {
+		t.Errorf("NewTest returned unexpected output, got %#v, want empty slice", test.Output)
+	}
+}
+
+func TestParseFatal(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestFatal"}
+{"Action":"output","Package":"package/name","Test":"TestFatal","Output":"=== RUN   TestFatal\n"}
+{"Action":"output","Package":"package/name","Test":"TestFatal","Output":"    main_test.go:6: setup done\n"}
+{"Action":"output","Package":"package/name","Test":"TestFatal","Output":"    main_test.go:7: FATAL: cannot connect to database\n"}
+{"Action":"output","Package":"package/name","Test":"TestFatal","Output":"--- FAIL: TestFatal (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestFatal","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestError"}
+{"Action":"output","Package":"package/name","Test":"TestError","Output":"=== RUN   TestError\n"}
+{"Action":"output","Package":"package/name","Test":"TestError","Output":"    main_test.go:12: Fatal error expected\n"}
+{"Action":"output","Package":"package/name","Test":"TestError","Output":"    main_test.go:13: got 1, want 2\n"}
+{"Action":"output","Package":"package/name","Test":"TestError","Output":"--- FAIL: TestError (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestError","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"TestFatal": true, "TestError": false}
+	for _, test := range report.Packages[0].Tests {
+		if test.Fatal != want[test.Name] {
+			t.Errorf("test %s has Fatal=%v, want %v", test.Name, test.Fatal, want[test.Name])
+		}
+	}
+}
+
+func TestParsePackageTimestamp(t *testing.T) {
+	input := `{"Time":"2022-01-01T10:00:00Z","Action":"run","Package":"package/name","Test":"TestOne"}
+{"Time":"2022-01-01T10:00:01Z","Action":"pass","Package":"package/name","Test":"TestOne","Elapsed":1}
+{"Time":"2022-01-01T10:00:02Z","Action":"pass","Package":"package/name","Elapsed":2}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC); !report.Packages[0].Timestamp.Equal(want) {
+		t.Errorf("unexpected package timestamp, got %v, want %v", report.Packages[0].Timestamp, want)
+	}
+}
+
+func TestParsePackageSummary(t *testing.T) {
+	input := `{"Action":"run","Package":"package/timed","Test":"TestOne"}
+{"Action":"pass","Package":"package/timed","Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":"package/timed","Output":"PASS\n"}
+{"Action":"output","Package":"package/timed","Output":"ok  \tpackage/timed\t1.234s\n"}
+{"Action":"pass","Package":"package/timed"}
+{"Action":"output","Package":"package/cached","Output":"ok  \tpackage/cached\t(cached)\n"}
+{"Action":"pass","Package":"package/cached"}
+{"Action":"output","Package":"package/elapsed","Output":"ok  \tpackage/elapsed\t0.500s\n"}
+{"Action":"pass","Package":"package/elapsed","Elapsed":0.75}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name     string
+		duration time.Duration
+		cached   bool
+	}{
+		{"package/timed", 1234 * time.Millisecond, false},
+		{"package/cached", 0, true},
+		{"package/elapsed", 750 * time.Millisecond, false},
+	}
+	if len(report.Packages) != len(want) {
+		t.Fatalf("unexpected number of packages, got %d, want %d", len(report.Packages), len(want))
+	}
+	for i, pkg := range report.Packages {
+		if pkg.Name != want[i].name || pkg.Duration != want[i].duration || pkg.Cached != want[i].cached {
+			t.Errorf("unexpected package, got name=%q duration=%v cached=%v, want name=%q duration=%v cached=%v",
+				pkg.Name, pkg.Duration, pkg.Cached, want[i].name, want[i].duration, want[i].cached)
+		}
+	}
+}
+
+func TestParsePackageNameFallback(t *testing.T) {
+	input := `{"Action":"run","Test":"TestOne"}
+{"Action":"pass","Test":"TestOne","Elapsed":0}
+{"Action":"run","Package":"package/other","Test":"TestTwo"}
+{"Action":"pass","Package":"package/other","Test":"TestTwo","Elapsed":0}
+{"Action":"output","Output":"PASS\n"}
+{"Action":"pass","Elapsed":0.1}
+`
+	report, err := Parse(strings.NewReader(input), "fallback/name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	for _, pkg := range report.Packages {
+		for _, test := range pkg.Tests {
+			if test.Package != pkg.Name {
+				t.Errorf("test %s in package %q has Package %q", test.Name, pkg.Name, test.Package)
+			}
+			got[pkg.Name] = append(got[pkg.Name], test.Name)
+		}
+	}
+	want := map[string][]string{
+		"fallback/name": {"TestOne"},
+		"package/other": {"TestTwo"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected tests per package, diff (-want, +got):\n%s\n", diff)
+	}
+	if len(report.Packages) != 2 {
+		t.Errorf("unexpected number of packages, got %d, want 2", len(report.Packages))
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	input := `{"Action":"run","Package":"package/a","Test":"TestOne"}
+{"Action":"pass","Package":"package/a","Test":"TestOne","Elapsed":0}
+{"Action":"run","Package":"package/a","Test":"TestTwo"}
+{"Action":"pass","Package":"package/a","Test":"TestTwo","Elapsed":0}
+{"Action":"pass","Package":"package/a","Elapsed":0}
+{"Action":"run","Package":"package/b","Test":"TestThree"}
+{"Action":"pass","Package":"package/b","Test":"TestThree","Elapsed":0}
+{"Action":"pass","Package":"package/b","Elapsed":0}
+`
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr bool
+	}{
+		{"unlimited", nil, false},
+		{"max packages not exceeded", []Option{MaxPackages(2)}, false},
+		{"max packages exceeded", []Option{MaxPackages(1)}, true},
+		{"max tests not exceeded", []Option{MaxTests(3)}, false},
+		{"max tests exceeded", []Option{MaxTests(2)}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewParser(test.options...).Parse(strings.NewReader(input))
+			if test.wantErr && err == nil {
+				t.Errorf("Parse did not return an error")
+			} else if !test.wantErr && err != nil {
+				t.Errorf("Parse returned unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseOutputAfterTerminalAction(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestOne"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"fail","Package":"package/name","Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"--- FAIL: TestOne (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := report.Packages[0].Tests
+	if len(tests) != 1 {
+		t.Fatalf("unexpected number of tests, got %d, want 1", len(tests))
+	}
+	want := []string{"=== RUN   TestOne\n", "--- FAIL: TestOne (0.00s)\n"}
+	if diff := cmp.Diff(want, tests[0].Output); diff != "" {
+		t.Errorf("unexpected test output, diff (-want, +got):\n%s\n", diff)
+	}
+	if tests[0].Result != FAIL {
+		t.Errorf("unexpected result, got %v, want %v", tests[0].Result, FAIL)
+	}
+}
+
+func TestParseStrictActions(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestOne"}
+{"Action":"teleport","Package":"package/name","Test":"TestOne"}
+{"Action":"pass","Package":"package/name","Test":"TestOne","Elapsed":0}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	report, err := NewParser(StrictActions(false)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse returned unexpected error in lenient mode: %v", err)
+	}
+	if got := report.Packages[0].Tests[0].Result; got != PASS {
+		t.Errorf("unexpected result in lenient mode, got %v, want %v", got, PASS)
+	}
+
+	if _, err := NewParser(StrictActions(true)).Parse(strings.NewReader(input)); err == nil {
+		t.Errorf("Parse did not return an error for an unknown action in strict mode")
+	}
+}
+
+func TestParseBlankOutputLines(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestDiff"}
+{"Action":"output","Package":"package/name","Test":"TestDiff","Output":"    main_test.go:6: diff:\n"}
+{"Action":"output","Package":"package/name","Test":"TestDiff","Output":"\n"}
+{"Action":"output","Package":"package/name","Test":"TestDiff","Output":"        -want\n"}
+{"Action":"output","Package":"package/name","Test":"TestDiff","Output":"\n"}
+{"Action":"output","Package":"package/name","Test":"TestDiff","Output":"        +got\n"}
+{"Action":"fail","Package":"package/name","Test":"TestDiff","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := report.Packages[0].Tests[0]
+	want := []string{"    main_test.go:6: diff:\n", "\n", "        -want\n", "\n", "        +got\n"}
+	if diff := cmp.Diff(want, test.Output); diff != "" {
+		t.Errorf("unexpected test output, diff (-want, +got):\n%s\n", diff)
+	}
+
+	tc := JUnitWriter{}.testsuites(report).Suites[0].Testcases[0]
+	if want := "    main_test.go:6: diff:\n\n        -want\n\n        +got\n"; tc.Failure == nil || tc.Failure.Data != want {
+		t.Errorf("unexpected failure output, got %+v, want %q", tc.Failure, want)
+	}
+}
+
+func TestParseTrackEvents(t *testing.T) {
+	input := `{"Time":"2022-01-01T10:00:00Z","Action":"run","Package":"package/name","Test":"TestA"}
+{"Time":"2022-01-01T10:00:00Z","Action":"output","Package":"package/name","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Time":"2022-01-01T10:00:01Z","Action":"output","Package":"package/name","Test":"TestA","Output":"=== PAUSE TestA\n"}
+{"Time":"2022-01-01T10:00:01Z","Action":"pause","Package":"package/name","Test":"TestA"}
+{"Time":"2022-01-01T10:00:02Z","Action":"run","Package":"package/name","Test":"TestB"}
+{"Time":"2022-01-01T10:00:02Z","Action":"output","Package":"package/name","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Time":"2022-01-01T10:00:03Z","Action":"output","Package":"package/name","Test":"TestB","Output":"=== PAUSE TestB\n"}
+{"Time":"2022-01-01T10:00:03Z","Action":"pause","Package":"package/name","Test":"TestB"}
+{"Time":"2022-01-01T10:00:04Z","Action":"output","Package":"package/name","Test":"TestB","Output":"=== CONT  TestA\n"}
+{"Time":"2022-01-01T10:00:05Z","Action":"output","Package":"package/name","Test":"TestA","Output":"=== CONT  TestB\n"}
+{"Time":"2022-01-01T10:00:05Z","Action":"output","Package":"package/name","Test":"TestA","Output":"=== CONT  TestMissing\n"}
+{"Time":"2022-01-01T10:00:06Z","Action":"pass","Package":"package/name","Test":"TestA","Elapsed":1}
+{"Time":"2022-01-01T10:00:07Z","Action":"pass","Package":"package/name","Test":"TestB","Elapsed":1}
+{"Time":"2022-01-01T10:00:07Z","Action":"pass","Package":"package/name","Elapsed":7}
+`
+	at := func(sec int) time.Time { return time.Date(2022, 1, 1, 10, 0, sec, 0, time.UTC) }
+	want := map[string][]TestEvent{
+		"TestA": {{"run", at(0)}, {"pause", at(1)}, {"cont", at(4)}},
+		"TestB": {{"run", at(2)}, {"pause", at(3)}, {"cont", at(5)}},
+	}
+
+	report, err := NewParser(TrackEvents(true)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]TestEvent)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = test.Events
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected test events, diff (-want, +got):\n%s\n", diff)
+	}
+
+	report, err = NewParser().Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range report.Packages[0].Tests {
+		if test.Events != nil {
+			t.Errorf("test %s has events without TrackEvents: %v", test.Name, test.Events)
+		}
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"results-linux.json": `{"Action":"run","Package":"package/a","Test":"TestLinux"}
+{"Action":"pass","Package":"package/a","Test":"TestLinux","Elapsed":0}
+{"Action":"pass","Package":"package/a","Elapsed":1}
+`,
+		"results-windows.json": `{"Action":"run","Package":"package/a","Test":"TestWindows"}
+{"Action":"fail","Package":"package/a","Test":"TestWindows","Elapsed":0}
+{"Action":"fail","Package":"package/a","Elapsed":2}
+{"Action":"pass","Package":"package/b","Elapsed":3}
+`,
+		"other.txt": `{"Action":"pass","Package":"package/ignored","Elapsed":1}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := ParseDir(dir, "results-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	for _, pkg := range report.Packages {
+		got[pkg.Name] = []string{}
+		for _, test := range pkg.Tests {
+			got[pkg.Name] = append(got[pkg.Name], test.Name)
+		}
+	}
+	want := map[string][]string{
+		"package/a": {"TestLinux", "TestWindows"},
+		"package/b": {},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected tests per package, diff (-want, +got):\n%s\n", diff)
+	}
+	if got, want := report.Packages[0].Duration, 3*time.Second; got != want {
+		t.Errorf("unexpected merged duration, got %v, want %v", got, want)
+	}
+}
+
+func TestParseInvalidElapsed(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestHuge"}
+{"Action":"pass","Package":"package/name","Test":"TestHuge","Elapsed":1e38}
+{"Action":"run","Package":"package/name","Test":"TestNegative"}
+{"Action":"pass","Package":"package/name","Test":"TestNegative","Elapsed":-1}
+{"Action":"pass","Package":"package/name","Elapsed":3e38}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := report.Packages[0]
+	if pkg.Duration != 0 {
+		t.Errorf("unexpected package duration, got %v, want 0", pkg.Duration)
+	}
+	for _, test := range pkg.Tests {
+		if test.Duration != 0 {
+			t.Errorf("unexpected duration for %s, got %v, want 0", test.Name, test.Duration)
+		}
+	}
+	if got, want := len(pkg.Warnings), 3; got != want {
+		t.Errorf("unexpected number of warnings, got %d, want %d: %q", got, want, pkg.Warnings)
+	}
+
+	for _, seconds := range []float32{float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1))} {
+		if d, ok := elapsed(seconds); d != 0 || ok {
+			t.Errorf("elapsed(%v) = %v, %v, want 0, false", seconds, d, ok)
+		}
+	}
+}
+
+func TestParseFuzzCorpus(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"FuzzReverse"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"=== RUN   FuzzReverse\n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"fuzz: elapsed: 0s, gathering baseline coverage: 0/3 completed\n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"--- FAIL: FuzzReverse (0.03s)\n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"    --- FAIL: FuzzReverse (0.00s)\n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"        reverse_test.go:20: Reverse produced invalid UTF-8 string \"\\x9c\\xdd\"\n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"    \n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"    Failing input written to testdata/fuzz/FuzzReverse/28f36ef487f23e6c\n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"    To re-run:\n"}
+{"Action":"output","Package":"package/name","Test":"FuzzReverse","Output":"    go test -run=FuzzReverse/28f36ef487f23e6c\n"}
+{"Action":"fail","Package":"package/name","Test":"FuzzReverse","Elapsed":0.03}
+{"Action":"run","Package":"package/name","Test":"TestReverse"}
+{"Action":"pass","Package":"package/name","Test":"TestReverse","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0.05}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = test.FuzzCorpus
+	}
+	want := map[string]string{
+		"FuzzReverse": "testdata/fuzz/FuzzReverse/28f36ef487f23e6c",
+		"TestReverse": "",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected FuzzCorpus, diff (-want, +got):\n%s\n", diff)
+	}
+	if got := report.Packages[0].Tests[0].Result; got != FAIL {
+		t.Errorf("unexpected result for FuzzReverse, got %v, want %v", got, FAIL)
+	}
+}
+
+func TestParseByteOrderMark(t *testing.T) {
+	input := "\xef\xbb\xbf" + `{"Action":"run","Package":"package/name","Test":"TestFirst"}
+{"Action":"pass","Package":"package/name","Test":"TestFirst","Elapsed":0}
+` + "\xef\xbb\xbf" + `{"Action":"run","Package":"package/name","Test":"TestSecond"}
+{"Action":"pass","Package":"package/name","Test":"TestSecond","Elapsed":0}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Packages) != 1 {
+		t.Fatalf("unexpected number of packages, got %d, want 1", len(report.Packages))
+	}
+
+	var got []string
+	for _, test := range report.Packages[0].Tests {
+		got = append(got, test.Name)
+	}
+	if diff := cmp.Diff([]string{"TestFirst", "TestSecond"}, got); diff != "" {
+		t.Errorf("unexpected tests, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseCoverage(t *testing.T) {
+	input := `{"Action":"run","Package":"package/a","Test":"TestA"}
+{"Action":"pass","Package":"package/a","Test":"TestA","Elapsed":0}
+{"Action":"output","Package":"package/a","Output":"PASS\n"}
+{"Action":"output","Package":"package/a","Output":"coverage: 75.0% of statements\n"}
+{"Action":"output","Package":"package/a","Output":"ok  \tpackage/a\t0.010s\tcoverage: 75.0% of statements\n"}
+{"Action":"pass","Package":"package/a","Elapsed":0.01}
+{"Action":"run","Package":"package/b","Test":"TestB"}
+{"Action":"pass","Package":"package/b","Test":"TestB","Elapsed":0}
+{"Action":"output","Package":"package/b","Output":"PASS\n"}
+{"Action":"output","Package":"package/b","Output":"coverage: 61.2% of statements in ./...\n"}
+{"Action":"output","Package":"package/b","Output":"ok  \tpackage/b\t0.010s\tcoverage: 61.2% of statements in ./...\n"}
+{"Action":"pass","Package":"package/b","Elapsed":0.01}
+{"Action":"pass","Package":"package/c","Elapsed":0.01}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, pkg := range report.Packages {
+		got[pkg.Name] = pkg.CoveragePct
+	}
+	want := map[string]string{
+		"package/a": "75.0",
+		"package/b": "61.2",
+		"package/c": "",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected coverage, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseBenchmarks(t *testing.T) {
+	input := `{"Action":"output","Package":"package/name","Output":"goos: linux\n"}
+{"Action":"run","Package":"package/name","Test":"BenchmarkFast"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkFast","Output":"=== RUN   BenchmarkFast\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkFast","Output":"BenchmarkFast\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkFast","Output":"BenchmarkFast-8   \t 2000000\t       512.5 ns/op\t      16 B/op\t       1 allocs/op\n"}
+{"Action":"pass","Package":"package/name","Test":"BenchmarkFast","Elapsed":1.2}
+{"Action":"run","Package":"package/name","Test":"BenchmarkCrash"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkCrash","Output":"=== RUN   BenchmarkCrash\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkCrash","Output":"BenchmarkCrash\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkCrash","Output":"--- FAIL: BenchmarkCrash\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkCrash","Output":"    bench_test.go:12: setup failed\n"}
+{"Action":"fail","Package":"package/name","Test":"BenchmarkCrash","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":1.5}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Benchmark{
+		{Name: "BenchmarkFast", Duration: 512 * time.Nanosecond, Bytes: 16, Allocs: 1, Result: PASS},
+		{Name: "BenchmarkCrash", Result: FAIL},
+	}
+	if diff := cmp.Diff(want, report.Packages[0].Benchmarks); diff != "" {
+		t.Errorf("unexpected benchmarks, diff (-want, +got):\n%s\n", diff)
+	}
+
+	var failures []string
+	for _, tc := range (JUnitWriter{}).testsuites(report).Suites[0].Testcases {
+		if tc.Failure != nil {
+			failures = append(failures, tc.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"BenchmarkCrash"}, failures); diff != "" {
+		t.Errorf("unexpected failed testcases, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestQuick"}
+{"Action":"pass","Package":"package/name","Test":"TestQuick","Elapsed":0.5}
+{"Action":"run","Package":"package/name","Test":"TestSlow"}
+{"Action":"fail","Package":"package/name","Test":"TestSlow","Elapsed":9.5}
+{"Action":"run","Package":"package/name","Test":"TestHanging"}
+{"Action":"output","Package":"package/name","Test":"TestHanging","Output":"panic: test timed out after 10s\n"}
+{"Action":"fail","Package":"package/name","Elapsed":10.1}
+`
+	report, err := NewParser(Timeout(10 * time.Second)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Timeout != 10*time.Second {
+		t.Errorf("unexpected report timeout, got %v, want %v", report.Timeout, 10*time.Second)
+	}
+
+	got := make(map[string]bool)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = test.LikelyTimedOut
+	}
+	want := map[string]bool{
+		"TestQuick":   false,
+		"TestSlow":    true,
+		"TestHanging": true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected LikelyTimedOut, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParsePropagateFailures(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestParent"}
+{"Action":"run","Package":"package/name","Test":"TestParent/child"}
+{"Action":"run","Package":"package/name","Test":"TestParent/child/grandchild"}
+{"Action":"fail","Package":"package/name","Test":"TestParent/child/grandchild","Elapsed":0}
+{"Action":"pass","Package":"package/name","Test":"TestParent/child","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestParent/sibling"}
+{"Action":"pass","Package":"package/name","Test":"TestParent/sibling","Elapsed":0}
+{"Action":"pass","Package":"package/name","Test":"TestParent","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestOther"}
+{"Action":"pass","Package":"package/name","Test":"TestOther","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	tests := []struct {
+		options []Option
+		want    map[string]Result
+	}{
+		{nil, map[string]Result{
+			"TestParent":                  FAIL,
+			"TestParent/child":            FAIL,
+			"TestParent/child/grandchild": FAIL,
+			"TestParent/sibling":          PASS,
+			"TestOther":                   PASS,
+		}},
+		{[]Option{PropagateFailures(false)}, map[string]Result{
+			"TestParent":                  PASS,
+			"TestParent/child":            PASS,
+			"TestParent/child/grandchild": FAIL,
+			"TestParent/sibling":          PASS,
+			"TestOther":                   PASS,
+		}},
+	}
+
+	for i, test := range tests {
+		report, err := NewParser(test.options...).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]Result)
+		for _, tc := range report.Packages[0].Tests {
+			got[tc.Name] = tc.Result
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("#%d: unexpected results, diff (-want, +got):\n%s\n", i, diff)
+		}
+	}
+}
+
+func TestParseMaxLineBytes(t *testing.T) {
+	long := strings.Repeat("x", 1024*1024)
+	input := `{"Action":"run","Package":"package/name","Test":"TestLong"}
+{"Action":"output","Package":"package/name","Test":"TestLong","Output":"` + long + `\n"}
+{"Action":"pass","Package":"package/name","Test":"TestLong","Elapsed":0}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	if _, err := NewParser(Echo(nil)).Parse(strings.NewReader(input)); err == nil {
+		t.Errorf("Parse with the default MaxLineBytes did not return an error")
+	}
+
+	report, err := NewParser(Echo(nil), MaxLineBytes(2*1024*1024)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := report.Packages[0].Tests[0].Output
+	if len(got) != 1 || got[0] != long+"\n" {
+		t.Errorf("long output line was not parsed correctly, got %d chunks", len(got))
+	}
+}
+
+func TestParseMaxLineBytesBelowBufferSize(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestLong"}
+{"Action":"output","Package":"package/name","Test":"TestLong","Output":"` + strings.Repeat("x", 10*1024) + `\n"}
+{"Action":"pass","Package":"package/name","Test":"TestLong","Elapsed":0}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	if _, err := NewParser(Echo(nil), MaxLineBytes(1024)).Parse(strings.NewReader(input)); err == nil {
+		t.Errorf("Parse with MaxLineBytes(1024) did not return an error for a 10KB line")
+	}
+	for _, n := range []int{0, -1} {
+		if _, err := NewParser(Echo(nil), MaxLineBytes(n)).Parse(strings.NewReader(input)); err != nil {
+			t.Errorf("Parse with MaxLineBytes(%d) returned an error: %v", n, err)
+		}
+	}
+}
+
+func TestParseNoTestsToRun(t *testing.T) {
+	input := `{"Action":"start","Package":"package/filtered"}
+{"Action":"output","Package":"package/filtered","Output":"testing: warning: no tests to run\n"}
+{"Action":"output","Package":"package/filtered","Output":"PASS\n"}
+{"Action":"output","Package":"package/filtered","Output":"ok  \tpackage/filtered\t0.010s [no tests to run]\n"}
+{"Action":"pass","Package":"package/filtered","Elapsed":0.01}
+{"Action":"run","Package":"package/matched","Test":"TestOne"}
+{"Action":"pass","Package":"package/matched","Test":"TestOne","Elapsed":0}
+{"Action":"pass","Package":"package/matched","Elapsed":0.01}
+`
+	// Echo is disabled, since go test itself also looks for this warning.
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]bool)
+	for _, pkg := range report.Packages {
+		got[pkg.Name] = pkg.NoTestsToRun
+	}
+	want := map[string]bool{"package/filtered": true, "package/matched": false}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected NoTestsToRun, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseInferAssertions(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestFail"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"=== RUN   TestFail\n"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"    main_test.go:10: got 1, want 2\n"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"    main_test.go:11: got 3, want 4\n"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"        continued message line\n"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"    main_test.go:12: got 5, want 6\n"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"--- FAIL: TestFail (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestFail","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestPass"}
+{"Action":"output","Package":"package/name","Test":"TestPass","Output":"    main_test.go:20: logged\n"}
+{"Action":"pass","Package":"package/name","Test":"TestPass","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	for _, enabled := range []bool{false, true} {
+		report, err := NewParser(InferAssertions(enabled)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]int)
+		for _, test := range report.Packages[0].Tests {
+			got[test.Name] = test.Assertions
+		}
+		want := map[string]int{"TestFail": 0, "TestPass": 0}
+		if enabled {
+			want["TestFail"] = 3
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("InferAssertions(%v): unexpected assertions, diff (-want, +got):\n%s\n", enabled, diff)
+		}
+	}
+}
+
+func TestParseBenchmarkStatus(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"BenchmarkSkipped"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkSkipped","Output":"=== RUN   BenchmarkSkipped\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkSkipped","Output":"--- SKIP: BenchmarkSkipped\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkSkipped","Output":"    bench_test.go:8: requires network\n"}
+{"Action":"skip","Package":"package/name","Test":"BenchmarkSkipped","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"BenchmarkLogged"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkLogged","Output":"BenchmarkLogged-8   \t 1000\t      1000 ns/op\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkLogged","Output":"--- BENCH: BenchmarkLogged-8\n"}
+{"Action":"output","Package":"package/name","Test":"BenchmarkLogged","Output":"    bench_test.go:15: running with N=1000\n"}
+{"Action":"bench","Package":"package/name","Test":"BenchmarkLogged"}
+{"Action":"output","Package":"package/name","Output":"--- SKIP: BenchmarkLegacy\n"}
+{"Action":"pass","Package":"package/name","Elapsed":1}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := report.Packages[0]
+
+	gotTests := make(map[string]Result)
+	for _, test := range pkg.Tests {
+		gotTests[test.Name] = test.Result
+	}
+	wantTests := map[string]Result{"BenchmarkSkipped": SKIP, "BenchmarkLogged": PASS}
+	if diff := cmp.Diff(wantTests, gotTests); diff != "" {
+		t.Errorf("unexpected test results, diff (-want, +got):\n%s\n", diff)
+	}
+
+	want := []*Benchmark{
+		{Name: "BenchmarkSkipped", Result: SKIP, Skipped: true},
+		{Name: "BenchmarkLogged", Duration: 1000 * time.Nanosecond, Result: PASS},
+		{Name: "BenchmarkLegacy", Result: SKIP, Skipped: true},
+	}
+	if diff := cmp.Diff(want, pkg.Benchmarks); diff != "" {
+		t.Errorf("unexpected benchmarks, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseConcurrent(t *testing.T) {
+	const n = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pkgName := fmt.Sprintf("package/p%d", i)
+			input := fmt.Sprintf(`{"Action":"run","Package":%[1]q,"Test":"TestOne"}
+{"Action":"output","Package":%[1]q,"Test":"TestOne","Output":"output of %[1]s\n"}
+{"Action":"fail","Package":%[1]q,"Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":%[1]q,"Output":"coverage: 50.0%% of statements\n"}
+{"Action":"fail","Package":%[1]q,"Elapsed":0.5}
+`, pkgName)
+
+			var echo bytes.Buffer
+			report, err := NewParser(Echo(&echo)).Parse(strings.NewReader(input))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(report.Packages) != 1 || report.Packages[0].Name != pkgName || report.Failures() != 1 {
+				errs <- fmt.Errorf("unexpected report for %s: %+v", pkgName, report.Packages)
+				return
+			}
+			if want := "output of " + pkgName + "\n"; !strings.HasPrefix(echo.String(), want) {
+				errs <- fmt.Errorf("unexpected echo for %s: %q", pkgName, echo.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestParseStartEnd(t *testing.T) {
+	input := `{"Time":"2022-01-01T10:00:00Z","Action":"run","Package":"package/name","Test":"TestOne"}
+{"Time":"2022-01-01T10:00:00.5Z","Action":"run","Package":"package/name","Test":"TestTwo"}
+{"Time":"2022-01-01T10:00:02Z","Action":"pass","Package":"package/name","Test":"TestOne","Elapsed":2}
+{"Action":"pass","Package":"package/name","Test":"TestTwo","Elapsed":3}
+{"Time":"2022-01-01T10:00:04Z","Action":"pass","Package":"package/name","Elapsed":4}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type span struct{ Start, End time.Time }
+	got := make(map[string]span)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = span{test.Start, test.End}
+	}
+	want := map[string]span{
+		"TestOne": {time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2022, 1, 1, 10, 0, 2, 0, time.UTC)},
+		"TestTwo": {time.Date(2022, 1, 1, 10, 0, 0, 5e8, time.UTC), time.Date(2022, 1, 1, 10, 0, 3, 5e8, time.UTC)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected start and end times, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseEchoLines(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestA"}
+{"Action":"run","Package":"package/name","Test":"TestB"}
+{"Action":"output","Package":"package/name","Test":"TestA","Output":"progress: "}
+{"Action":"output","Package":"package/name","Test":"TestB","Output":"loading"}
+{"Action":"output","Package":"package/name","Test":"TestA","Output":"50%"}
+{"Action":"output","Package":"package/name","Test":"TestB","Output":" done\nnext "}
+{"Action":"output","Package":"package/name","Test":"TestA","Output":" 100%\n"}
+{"Action":"pass","Package":"package/name","Test":"TestA","Elapsed":0}
+{"Action":"output","Package":"package/name","Test":"TestB","Output":"line\n"}
+{"Action":"output","Package":"package/name","Test":"TestB","Output":"unterminated"}
+{"Action":"pass","Package":"package/name","Test":"TestB","Elapsed":0}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	tests := []struct {
+		lines bool
+		want  string
+	}{
+		{false, "progress: loading50% done\nnext  100%\nline\nunterminated"},
+		{true, "loading done\nprogress: 50% 100%\nnext line\nunterminated"},
+	}
+
+	for _, test := range tests {
+		var echo bytes.Buffer
+		if _, err := NewParser(Echo(&echo), EchoLines(test.lines)).Parse(strings.NewReader(input)); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, echo.String()); diff != "" {
+			t.Errorf("EchoLines(%v): unexpected echo, diff (-want, +got):\n%s\n", test.lines, diff)
+		}
+	}
+}
+
+func TestParseUnusualNames(t *testing.T) {
+	input := `{"Action":"run","Package":"package/名前","Test":"TestTab\tName"}
+{"Action":"output","Package":"package/名前","Test":"TestTab\tName","Output":"=== RUN   TestTab\tName\n"}
+{"Action":"output","Package":"package/名前","Test":"TestTab\tName","Output":"    main_test.go:5: <tab>\t&\n"}
+{"Action":"fail","Package":"package/名前","Test":"TestTab\tName","Elapsed":0}
+{"Action":"run","Package":"package/名前","Test":"Test日本語"}
+{"Action":"output","Package":"package/名前","Test":"Test日本語","Output":"=== RUN   Test日本語\n"}
+{"Action":"run","Package":"package/名前","Test":"Test日本語/サブテスト"}
+{"Action":"output","Package":"package/名前","Test":"Test日本語/サブテスト","Output":"=== RUN   Test日本語/サブテスト\n"}
+{"Action":"pass","Package":"package/名前","Test":"Test日本語/サブテスト","Elapsed":0}
+{"Action":"pass","Package":"package/名前","Test":"Test日本語","Elapsed":0}
+{"Action":"fail","Package":"package/名前","Elapsed":0}
+`
+	report, err := NewParser(TrackEvents(true)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]int)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = len(test.Events)
+	}
+	want := map[string]int{"TestTab\tName": 1, "Test日本語": 1, "Test日本語/サブテスト": 1}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected tests and number of events, diff (-want, +got):\n%s\n", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{NestSubtests: true}).Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	var suites xmlTestsuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, buf.String())
+	}
+	suite := suites.Suites[0]
+	if suite.Name != "package/名前" {
+		t.Errorf("unexpected testsuite name, got %q", suite.Name)
+	}
+	if len(suite.Testcases) != 1 || suite.Testcases[0].Name != "TestTab\tName" {
+		t.Errorf("unexpected testcases, got %+v", suite.Testcases)
+	} else if got, want := suite.Testcases[0].Failure.Data, "=== RUN   TestTab\tName\n    main_test.go:5: <tab>\t&\n"; got != want {
+		t.Errorf("unexpected failure output, got %q, want %q", got, want)
+	}
+	if len(suite.Suites) != 1 || suite.Suites[0].Name != "Test日本語" ||
+		len(suite.Suites[0].Testcases) != 1 || suite.Suites[0].Testcases[0].Name != "Test日本語/サブテスト" {
+		t.Errorf("unexpected nested testsuites, got %+v", suite.Suites)
+	}
+}
+
+func TestParseOwnerMarker(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestOwned"}
+{"Action":"output","Package":"package/name","Test":"TestOwned","Output":"    main_test.go:10: OWNER: team-foo\n"}
+{"Action":"output","Package":"package/name","Test":"TestOwned","Output":"    main_test.go:11: something failed\n"}
+{"Action":"output","Package":"package/name","Test":"TestOwned","Output":"OWNER: team-bar\n"}
+{"Action":"fail","Package":"package/name","Test":"TestOwned","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestUnowned"}
+{"Action":"output","Package":"package/name","Test":"TestUnowned","Output":"    main_test.go:20: the OWNER: is unknown\n"}
+{"Action":"pass","Package":"package/name","Test":"TestUnowned","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	report, err := NewParser(OwnerMarker("OWNER:")).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = test.Owner
+	}
+	want := map[string]string{"TestOwned": "team-bar", "TestUnowned": ""}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected owners, diff (-want, +got):\n%s\n", diff)
+	}
+
+	tc := (JUnitWriter{}).testsuites(report).Suites[0].Testcases[0]
+	if diff := cmp.Diff(&[]xmlProperty{{Name: "owner", Value: "team-bar"}}, tc.Properties); diff != "" {
+		t.Errorf("unexpected testcase properties, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseRaceEnabled(t *testing.T) {
+	race := `{"Action":"run","Package":"package/racy","Test":"TestRace"}
+{"Action":"output","Package":"package/racy","Test":"TestRace","Output":"==================\n"}
+{"Action":"output","Package":"package/racy","Test":"TestRace","Output":"WARNING: DATA RACE\n"}
+{"Action":"output","Package":"package/racy","Test":"TestRace","Output":"Write at 0x00c000012345 by goroutine 8:\n"}
+{"Action":"output","Package":"package/racy","Test":"TestRace","Output":"    testing.go:1398: race detected during execution of test\n"}
+{"Action":"fail","Package":"package/racy","Test":"TestRace","Elapsed":0}
+{"Action":"fail","Package":"package/racy","Elapsed":0}
+{"Action":"run","Package":"package/clean","Test":"TestClean"}
+{"Action":"pass","Package":"package/clean","Test":"TestClean","Elapsed":0}
+{"Action":"pass","Package":"package/clean","Elapsed":0}
+`
+	normal := `{"Action":"run","Package":"package/clean","Test":"TestClean"}
+{"Action":"pass","Package":"package/clean","Test":"TestClean","Elapsed":0}
+{"Action":"pass","Package":"package/clean","Elapsed":0}
+`
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{race, true},
+		{normal, false},
+	}
+
+	for i, test := range tests {
+		report, err := NewParser(Echo(nil)).Parse(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.RaceEnabled != test.want {
+			t.Errorf("#%d: unexpected RaceEnabled, got %v, want %v", i, report.RaceEnabled, test.want)
+		}
+		if got := report.Packages[0].DataRace; got != test.want {
+			t.Errorf("#%d: unexpected DataRace for %s, got %v, want %v", i, report.Packages[0].Name, got, test.want)
+		}
+	}
+}
+
+func TestParseOutputRewriter(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestLogin"}
+{"Action":"output","Package":"package/name","Test":"TestLogin","Output":"    login_test.go:10: using token=abc123secret\n"}
+{"Action":"output","Package":"package/name","Test":"TestLogin","Output":"    login_test.go:11: DEBUG noise\n"}
+{"Action":"output","Package":"package/name","Test":"TestLogin","Output":"    login_test.go:12: login failed\n"}
+{"Action":"fail","Package":"package/name","Test":"TestLogin","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	token := regexp.MustCompile(`token=\S+`)
+	rewriter := func(s string) string {
+		if strings.Contains(s, "DEBUG") {
+			return ""
+		}
+		return token.ReplaceAllString(s, "token=***")
+	}
+
+	report, err := NewParser(OutputRewriter(rewriter)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"    login_test.go:10: using token=***\n",
+		"    login_test.go:12: login failed\n",
+	}
+	if diff := cmp.Diff(want, report.Packages[0].Tests[0].Output); diff != "" {
+		t.Errorf("unexpected output, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseEchoFailuresOnly(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestPass"}
+{"Action":"output","Package":"package/name","Test":"TestPass","Output":"=== RUN   TestPass\n"}
+{"Action":"run","Package":"package/name","Test":"TestFail"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"=== RUN   TestFail\n"}
+{"Action":"output","Package":"package/name","Test":"TestPass","Output":"    verbose output\n"}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"    main_test.go:5: failed\n"}
+{"Action":"output","Package":"package/name","Test":"TestPass","Output":"--- PASS: TestPass (0.00s)\n"}
+{"Action":"pass","Package":"package/name","Test":"TestPass","Elapsed":0}
+{"Action":"output","Package":"package/name","Test":"TestFail","Output":"--- FAIL: TestFail (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestFail","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestKilled"}
+{"Action":"output","Package":"package/name","Test":"TestKilled","Output":"=== RUN   TestKilled\n"}
+{"Action":"output","Package":"package/name","Output":"FAIL\n"}
+`
+	var echo bytes.Buffer
+	if _, err := NewParser(Echo(&echo), EchoFailuresOnly(true)).Parse(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	want := "=== RUN   TestFail\n    main_test.go:5: failed\n--- FAIL: TestFail (0.00s)\nFAIL\n=== RUN   TestKilled\n"
+	if diff := cmp.Diff(want, echo.String()); diff != "" {
+		t.Errorf("unexpected echo, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseStateReleasesCompletedPackages(t *testing.T) {
+	s := newParseState(NewParser(Echo(nil)))
+	events := []LineOutput{
+		{Action: "run", Package: "package/a", Test: "TestA"},
+		{Action: "run", Package: "package/b", Test: "TestB"},
+		{Action: "pass", Package: "package/a", Test: "TestA"},
+		{Action: "pass", Package: "package/a"},
+	}
+	for _, event := range events {
+		if _, err := s.handle(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s.findTest("TestA", "package/a") != nil {
+		t.Errorf("test of completed package is still tracked")
+	}
+	if s.findTest("TestB", "package/b") == nil {
+		t.Errorf("test of running package is no longer tracked")
+	}
+	if len(s.tests) != 1 || len(s.byName) != 1 {
+		t.Errorf("unexpected number of tracked tests, got %d in order and %d by name, want 1", len(s.tests), len(s.byName))
+	}
+}
+
+func TestEchoFailuresOnlyReleasesCompletedTests(t *testing.T) {
+	e := newEchoWriter(&bytes.Buffer{}, false, true)
+	e.write(LineOutput{Action: "output", Package: "package/name", Test: "TestDone", Output: "done\n"})
+	e.write(LineOutput{Action: "output", Package: "package/name", Test: "TestRunning", Output: "running\n"})
+	e.write(LineOutput{Action: "pass", Package: "package/name", Test: "TestDone"})
+
+	if _, ok := e.buffered["package/name\x00TestDone"]; ok || len(e.buffered) != 1 {
+		t.Errorf("output of completed test is still buffered: %v", e.buffered)
+	}
+	if want := []string{"package/name\x00TestRunning"}; !cmp.Equal(want, e.bufferedOrder) {
+		t.Errorf("unexpected buffered order, got %q, want %q", e.bufferedOrder, want)
+	}
+}
+
+func TestParseExamples(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"ExampleHello"}
+{"Action":"output","Package":"package/name","Test":"ExampleHello","Output":"=== RUN   ExampleHello\n"}
+{"Action":"output","Package":"package/name","Test":"ExampleHello","Output":"--- PASS: ExampleHello (0.00s)\n"}
+{"Action":"pass","Package":"package/name","Test":"ExampleHello","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"ExampleBenchmarkLike"}
+{"Action":"output","Package":"package/name","Test":"ExampleBenchmarkLike","Output":"=== RUN   ExampleBenchmarkLike\n"}
+{"Action":"output","Package":"package/name","Test":"ExampleBenchmarkLike","Output":"--- FAIL: ExampleBenchmarkLike (0.00s)\n"}
+{"Action":"output","Package":"package/name","Test":"ExampleBenchmarkLike","Output":"got:\n"}
+{"Action":"output","Package":"package/name","Test":"ExampleBenchmarkLike","Output":"hello\n"}
+{"Action":"output","Package":"package/name","Test":"ExampleBenchmarkLike","Output":"want:\n"}
+{"Action":"output","Package":"package/name","Test":"ExampleBenchmarkLike","Output":"goodbye\n"}
+{"Action":"fail","Package":"package/name","Test":"ExampleBenchmarkLike","Elapsed":0}
+{"Action":"output","Package":"package/name","Output":"FAIL\n"}
+{"Action":"fail","Package":"package/name","Elapsed":0.01}
+`
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := report.Packages[0]
+
+	got := make(map[string]Result)
+	for _, test := range pkg.Tests {
+		got[test.Name] = test.Result
+	}
+	want := map[string]Result{"ExampleHello": PASS, "ExampleBenchmarkLike": FAIL}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected example results, diff (-want, +got):\n%s\n", diff)
+	}
+	if len(pkg.Benchmarks) != 0 {
+		t.Errorf("examples were parsed as benchmarks: %+v", pkg.Benchmarks)
+	}
+	if got := pkg.Tests[1].Output; len(got) != 6 {
+		t.Errorf("unexpected output of failed example, got %q", got)
+	}
+}
+
+func TestParseLeakDetected(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestLeaky"}
+{"Action":"output","Package":"package/name","Test":"TestLeaky","Output":"=== RUN   TestLeaky\n"}
+{"Action":"output","Package":"package/name","Test":"TestLeaky","Output":"    leak_test.go:15: found unexpected goroutines:\n"}
+{"Action":"output","Package":"package/name","Test":"TestLeaky","Output":"        [Goroutine 7 in state chan receive, with example.com/pkg.worker on top of the stack:\n"}
+{"Action":"output","Package":"package/name","Test":"TestLeaky","Output":"        example.com/pkg.worker(0xc000010000)\n"}
+{"Action":"output","Package":"package/name","Test":"TestLeaky","Output":"        ]\n"}
+{"Action":"output","Package":"package/name","Test":"TestLeaky","Output":"--- FAIL: TestLeaky (0.45s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestLeaky","Elapsed":0.45}
+{"Action":"run","Package":"package/name","Test":"TestFine"}
+{"Action":"pass","Package":"package/name","Test":"TestFine","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0.5}
+`
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := report.Packages[0]
+
+	leaky := pkg.Tests[0]
+	if !leaky.LeakDetected {
+		t.Errorf("LeakDetected not set for %s", leaky.Name)
+	}
+	if pkg.Tests[1].LeakDetected {
+		t.Errorf("LeakDetected set for %s", pkg.Tests[1].Name)
+	}
+	if diff := cmp.Diff(Stats{Total: 2, Passed: 1, Errored: 1, Duration: 500 * time.Millisecond}, pkg.Stats()); diff != "" {
+		t.Errorf("unexpected stats, diff (-want, +got):\n%s\n", diff)
+	}
+
+	tc := (JUnitWriter{}).testsuites(report).Suites[0].Testcases[0]
+	if tc.Error == nil || tc.Failure != nil {
+		t.Fatalf("leaking test was not written as an error: %+v", tc)
+	}
+	if !strings.Contains(tc.Error.Data, "Goroutine 7 in state chan receive") {
+		t.Errorf("leak report missing from error output: %q", tc.Error.Data)
+	}
+}
+
+func TestParsePackageErrors(t *testing.T) {
+	input := `{"Action":"start","Package":"package/setup"}
+{"Action":"output","Package":"package/setup","Output":"main_test.go:14: setup failed: connection refused\n"}
+{"Action":"output","Package":"package/setup","Output":"FAIL\tpackage/setup\t0.005s\n"}
+{"Action":"fail","Package":"package/setup","Elapsed":0.005}
+{"Action":"start","Package":"package/teardown"}
+{"Action":"run","Package":"package/teardown","Test":"TestOne"}
+{"Action":"output","Package":"package/teardown","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"package/teardown","Test":"TestOne","Output":"--- PASS: TestOne (0.00s)\n"}
+{"Action":"pass","Package":"package/teardown","Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":"package/teardown","Output":"PASS\n"}
+{"Action":"output","Package":"package/teardown","Output":"main_test.go:20: teardown failed\n"}
+{"Action":"output","Package":"package/teardown","Output":"FAIL\tpackage/teardown\t0.010s\n"}
+{"Action":"fail","Package":"package/teardown","Elapsed":0.01}
+{"Action":"start","Package":"package/failed"}
+{"Action":"run","Package":"package/failed","Test":"TestFail"}
+{"Action":"fail","Package":"package/failed","Test":"TestFail","Elapsed":0}
+{"Action":"output","Package":"package/failed","Output":"FAIL\n"}
+{"Action":"fail","Package":"package/failed","Elapsed":0.01}
+`
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]PackageError)
+	for _, pkg := range report.Packages {
+		got[pkg.Name] = pkg.Errors
+	}
+	want := map[string][]PackageError{
+		"package/setup":    {{Phase: "setup", Output: []string{"main_test.go:14: setup failed: connection refused\n"}}},
+		"package/teardown": {{Phase: "teardown", Output: []string{"main_test.go:20: teardown failed\n"}}},
+		"package/failed":   nil,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected package errors, diff (-want, +got):\n%s\n", diff)
+	}
+
+	suite := (JUnitWriter{}).testsuites(report).Suites[0]
+	if suite.Errors != 1 || len(suite.Testcases) != 1 {
+		t.Fatalf("setup failure was not written as an errored testcase: %+v", suite)
+	}
+	tc := suite.Testcases[0]
+	if tc.Name != "setup" || tc.Error == nil || !strings.Contains(tc.Error.Data, "connection refused") {
+		t.Errorf("unexpected setup testcase: %+v", tc)
+	}
+}
+
+func TestParseStripSchedulingMarkers(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestParallel"}
+{"Action":"output","Package":"package/name","Test":"TestParallel","Output":"=== RUN   TestParallel\n"}
+{"Action":"output","Package":"package/name","Test":"TestParallel","Output":"=== PAUSE TestParallel\n"}
+{"Action":"output","Package":"package/name","Test":"TestParallel","Output":"=== CONT  TestParallel\n"}
+{"Action":"output","Package":"package/name","Test":"TestParallel","Output":"    main_test.go:10: got 1, want 2\n"}
+{"Action":"output","Package":"package/name","Test":"TestParallel","Output":"--- FAIL: TestParallel (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestParallel","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	for _, enabled := range []bool{false, true} {
+		report, err := NewParser(Echo(nil), StripSchedulingMarkers(enabled)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"    main_test.go:10: got 1, want 2\n",
+			"--- FAIL: TestParallel (0.00s)\n",
+		}
+		if !enabled {
+			want = append([]string{
+				"=== RUN   TestParallel\n",
+				"=== PAUSE TestParallel\n",
+				"=== CONT  TestParallel\n",
+			}, want...)
+		}
+		if diff := cmp.Diff(want, report.Packages[0].Tests[0].Output); diff != "" {
+			t.Errorf("StripSchedulingMarkers(%v): unexpected output, diff (-want, +got):\n%s\n", enabled, diff)
+		}
+	}
+}
+
+func TestParseJSONArray(t *testing.T) {
+	events := []string{
+		`{"Action":"run","Package":"package/name","Test":"TestOne"}`,
+		`{"Action":"output","Package":"package/name","Test":"TestOne","Output":"--- PASS: TestOne (0.01s)\n"}`,
+		`{"Action":"pass","Package":"package/name","Test":"TestOne","Elapsed":0.01}`,
+		`{"Action":"run","Package":"package/name","Test":"TestTwo"}`,
+		`{"Action":"output","Package":"package/name","Test":"TestTwo","Output":"--- FAIL: TestTwo (0.02s)\n"}`,
+		`{"Action":"fail","Package":"package/name","Test":"TestTwo","Elapsed":0.02}`,
+		`{"Action":"fail","Package":"package/name","Elapsed":0.03}`,
+	}
+	want, err := NewParser(Echo(nil)).Parse(strings.NewReader(strings.Join(events, "\n") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{
+		"[" + strings.Join(events, ",") + "]",
+		"\xef\xbb\xbf\n  [\n" + strings.Join(events, ",\n") + "\n]\n",
+	} {
+		got, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", input, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Parse(%q) unexpected report, diff (-want, +got):\n%s\n", input, diff)
+		}
+	}
+
+	if _, err := NewParser(Echo(nil)).Parse(strings.NewReader("[" + events[0] + ",{")); err == nil {
+		t.Error("Parse of invalid JSON array did not return an error")
+	}
+}
+
+func TestParseTrackAttempts(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestFlaky"}
+{"Action":"fail","Package":"package/name","Test":"TestFlaky","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestFlaky"}
+{"Action":"fail","Package":"package/name","Test":"TestFlaky","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestFlaky"}
+{"Action":"pass","Package":"package/name","Test":"TestFlaky","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	for _, enabled := range []bool{false, true} {
+		report, err := NewParser(Echo(nil), TrackAttempts(enabled)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		test := report.Packages[0].Tests[0]
+		want := []Result{PASS}
+		if enabled {
+			want = []Result{FAIL, FAIL, PASS}
+		}
+		if diff := cmp.Diff(want, test.ResultHistory()); diff != "" {
+			t.Errorf("TrackAttempts(%v): unexpected result history, diff (-want, +got):\n%s\n", enabled, diff)
+		}
+	}
+}
+
+func TestParseWithStderr(t *testing.T) {
+	stdout := `{"Action":"run","Package":"package/name","Test":"TestOne"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"--- FAIL: TestOne (0.00s)\n"}
+{"Action":"fail","Package":"package/name","Test":"TestOne","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestTwo"}
+{"Action":"pass","Package":"package/name","Test":"TestTwo","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	stderr := `{"Action":"output","Package":"package/name","Test":"TestOne","Output":"connecting to database\n"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"connection refused\n"}
+{"Action":"output","Package":"package/name","Test":"TestMissing","Output":"dropped\n"}
+{"Action":"output","Package":"package/name","Output":"package output\n"}
+`
+	report, err := NewParser(Echo(nil)).ParseWithStderr(strings.NewReader(stdout), strings.NewReader(stderr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := report.Packages[0].Tests
+	if diff := cmp.Diff([]string{"=== RUN   TestOne\n", "--- FAIL: TestOne (0.00s)\n"}, tests[0].Output); diff != "" {
+		t.Errorf("unexpected output, diff (-want, +got):\n%s\n", diff)
+	}
+	if diff := cmp.Diff([]string{"connecting to database\n", "connection refused\n"}, tests[0].Stderr); diff != "" {
+		t.Errorf("unexpected stderr, diff (-want, +got):\n%s\n", diff)
+	}
+	if tests[1].Stderr != nil {
+		t.Errorf("unexpected stderr for %s: %q", tests[1].Name, tests[1].Stderr)
+	}
+
+	tc := (JUnitWriter{}).testsuites(report).Suites[0].Testcases[0]
+	if tc.SystemErr == nil || tc.SystemErr.Data != "connecting to database\nconnection refused\n" {
+		t.Errorf("unexpected system-err: %+v", tc.SystemErr)
+	}
+	if tc.Failure == nil || strings.Contains(tc.Failure.Data, "connection refused") {
+		t.Errorf("unexpected failure: %+v", tc.Failure)
+	}
+}
+
+func TestParseInferPackageName(t *testing.T) {
+	input := `{"Action":"run","Test":"TestPanic"}
+{"Action":"output","Test":"TestPanic","Output":"--- FAIL: TestPanic (0.00s)\n"}
+{"Action":"output","Test":"TestPanic","Output":"panic: boom [recovered]\n"}
+{"Action":"output","Test":"TestPanic","Output":"\t/usr/local/go/src/testing/testing.go:1545 +0x238\n"}
+{"Action":"output","Test":"TestPanic","Output":"\t/home/user/src/example.com/foo/bar/bar_test.go:12 +0x18\n"}
+{"Action":"fail","Test":"TestPanic","Elapsed":0}
+{"Action":"run","Test":"TestOther"}
+{"Action":"output","Test":"TestOther","Output":"\t/home/user/src/example.com/foo/bar/other_test.go:34 +0x18\n"}
+{"Action":"fail","Test":"TestOther","Elapsed":0}
+{"Action":"fail","Elapsed":0}
+`
+	tests := []struct {
+		options []Option
+		want    string
+	}{
+		{[]Option{PackageName("fallback")}, "fallback"},
+		{[]Option{PackageName("fallback"), InferPackageName(true)}, "/home/user/src/example.com/foo/bar"},
+	}
+	for _, test := range tests {
+		report, err := NewParser(append(test.options, Echo(nil))...).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Packages) != 1 {
+			t.Fatalf("unexpected number of packages, got %d, want 1", len(report.Packages))
+		}
+		pkg := report.Packages[0]
+		if pkg.Name != test.want {
+			t.Errorf("unexpected package name, got %q, want %q", pkg.Name, test.want)
+		}
+		for _, tt := range pkg.Tests {
+			if tt.Package != test.want {
+				t.Errorf("unexpected package of %s, got %q, want %q", tt.Name, tt.Package, test.want)
+			}
+		}
+	}
+
+	// Without full paths the name cannot be inferred.
+	input = `{"Action":"run","Test":"TestFail"}
+{"Action":"output","Test":"TestFail","Output":"    main_test.go:10: failed\n"}
+{"Action":"fail","Test":"TestFail","Elapsed":0}
+`
+	report, err := NewParser(PackageName("fallback"), InferPackageName(true), Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Packages[0].Name; got != "fallback" {
+		t.Errorf("unexpected package name, got %q, want %q", got, "fallback")
+	}
+}
+
+func TestParseMaxParallel(t *testing.T) {
+	input := `{"Time":"2024-01-01T10:00:00Z","Action":"run","Package":"package/name","Test":"TestParallel"}
+{"Time":"2024-01-01T10:00:00Z","Action":"run","Package":"package/name","Test":"TestParallel/a"}
+{"Time":"2024-01-01T10:00:01Z","Action":"run","Package":"package/name","Test":"TestParallel/b"}
+{"Time":"2024-01-01T10:00:02Z","Action":"run","Package":"package/name","Test":"TestParallel/c"}
+{"Time":"2024-01-01T10:00:03Z","Action":"pass","Package":"package/name","Test":"TestParallel/a","Elapsed":3}
+{"Time":"2024-01-01T10:00:04Z","Action":"pass","Package":"package/name","Test":"TestParallel/b","Elapsed":3}
+{"Time":"2024-01-01T10:00:04Z","Action":"run","Package":"package/name","Test":"TestParallel/d"}
+{"Time":"2024-01-01T10:00:05Z","Action":"pass","Package":"package/name","Test":"TestParallel/c","Elapsed":3}
+{"Time":"2024-01-01T10:00:06Z","Action":"pass","Package":"package/name","Test":"TestParallel/d","Elapsed":2}
+{"Time":"2024-01-01T10:00:06Z","Action":"pass","Package":"package/name","Test":"TestParallel","Elapsed":6}
+{"Time":"2024-01-01T10:00:06Z","Action":"run","Package":"package/name","Test":"TestSerial"}
+{"Time":"2024-01-01T10:00:07Z","Action":"pass","Package":"package/name","Test":"TestSerial","Elapsed":1}
+{"Time":"2024-01-01T10:00:07Z","Action":"pass","Package":"package/name","Elapsed":7}
+{"Action":"run","Package":"package/untimed","Test":"TestOne"}
+{"Action":"pass","Package":"package/untimed","Test":"TestOne","Elapsed":0}
+{"Action":"pass","Package":"package/untimed","Elapsed":0}
+`
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]int)
+	for _, pkg := range report.Packages {
+		got[pkg.Name] = pkg.MaxParallel
+	}
+	want := map[string]int{"package/name": 3, "package/untimed": 0}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected MaxParallel, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParseOrphanedSubtests(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestParent"}
+{"Action":"run","Package":"package/name","Test":"TestParent/done"}
+{"Action":"pass","Package":"package/name","Test":"TestParent/done","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestParent/orphan"}
+{"Action":"output","Package":"package/name","Test":"TestParent/orphan","Output":"=== RUN   TestParent/orphan\n"}
+{"Action":"pass","Package":"package/name","Test":"TestParent","Elapsed":0}
+{"Action":"run","Package":"package/name","Test":"TestKilled"}
+{"Action":"run","Package":"package/name","Test":"TestKilled/child"}
+{"Action":"pass","Package":"package/name","Elapsed":0}
+`
+	type result struct {
+		Result     Result
+		Incomplete bool
+		Orphaned   bool
+	}
+	tests := []struct {
+		skip bool
+		want map[string]result
+	}{
+		{false, map[string]result{
+			"TestParent":        {Result: PASS},
+			"TestParent/done":   {Result: PASS},
+			"TestParent/orphan": {Result: FAIL, Incomplete: true, Orphaned: true},
+			"TestKilled":        {Result: FAIL},
+			"TestKilled/child":  {Result: FAIL},
+		}},
+		{true, map[string]result{
+			"TestParent":        {Result: PASS},
+			"TestParent/done":   {Result: PASS},
+			"TestParent/orphan": {Result: SKIP, Orphaned: true},
+			"TestKilled":        {Result: FAIL},
+			"TestKilled/child":  {Result: FAIL},
+		}},
+	}
+	for _, test := range tests {
+		report, err := NewParser(Echo(nil), SkipOrphanedSubtests(test.skip)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]result)
+		for _, tt := range report.Packages[0].Tests {
+			got[tt.Name] = result{tt.Result, tt.Incomplete, tt.Orphaned}
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("SkipOrphanedSubtests(%v): unexpected results, diff (-want, +got):\n%s\n", test.skip, diff)
+		}
+	}
+}
+
+func TestJSONOmitsDeprecatedTime(t *testing.T) {
+	pkg := &Package{
+		Name:     "package/name",
+		Duration: 1500 * time.Millisecond,
+		Time:     1500,
+		Tests:    []*Test{{Name: "TestOne", Duration: 2 * time.Millisecond, Time: 2}},
+	}
+	data, err := json.Marshal(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Time     *int
+		Duration int64
+		Tests    []map[string]interface{}
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Time != nil {
+		t.Errorf("deprecated Time field of package encoded: %s", data)
+	}
+	if got.Duration != int64(1500*time.Millisecond) {
+		t.Errorf("unexpected package Duration, got %d, want %d", got.Duration, int64(1500*time.Millisecond))
+	}
+	if _, ok := got.Tests[0]["Time"]; ok {
+		t.Errorf("deprecated Time field of test encoded: %s", data)
+	}
+}
+
+func TestParseElapsedString(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestNumber"}
+{"Action":"pass","Package":"package/name","Test":"TestNumber","Elapsed":1.5}
+{"Action":"run","Package":"package/name","Test":"TestString"}
+{"Action":"pass","Package":"package/name","Test":"TestString","Elapsed":"1.23s"}
+{"Action":"run","Package":"package/name","Test":"TestMillis"}
+{"Action":"pass","Package":"package/name","Test":"TestMillis","Elapsed":"250ms"}
+{"Action":"run","Package":"package/name","Test":"TestInvalid"}
+{"Action":"pass","Package":"package/name","Test":"TestInvalid","Elapsed":"soon"}
+{"Action":"pass","Package":"package/name","Elapsed":"3s"}
+`
+	report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := report.Packages[0]
+
+	got := make(map[string]time.Duration)
+	for _, test := range pkg.Tests {
+		got[test.Name] = test.Duration.Round(time.Millisecond)
+	}
+	want := map[string]time.Duration{
+		"TestNumber":  1500 * time.Millisecond,
+		"TestString":  1230 * time.Millisecond,
+		"TestMillis":  250 * time.Millisecond,
+		"TestInvalid": 0,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected durations, diff (-want, +got):\n%s\n", diff)
+	}
+	if got, want := pkg.Duration, 3*time.Second; got != want {
+		t.Errorf("unexpected package duration, got %v, want %v", got, want)
+	}
+	if len(pkg.Warnings) != 1 || !strings.Contains(pkg.Warnings[0], "TestInvalid") {
+		t.Errorf("unexpected warnings: %q", pkg.Warnings)
+	}
+}
+
+func TestParseWithStderrRewritesOutput(t *testing.T) {
+	stdout := `{"Action":"run","Package":"package/name","Test":"TestOne"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"stdout SECRET\n"}
+{"Action":"fail","Package":"package/name","Test":"TestOne","Elapsed":0}
+{"Action":"fail","Package":"package/name","Elapsed":0}
+`
+	stderr := `{"Action":"output","Package":"package/name","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"package/name","Test":"TestOne","Output":"stderr SECRET\n"}
+`
+	rewriter := func(s string) string { return strings.ReplaceAll(s, "SECRET", "***") }
+	parser := NewParser(Echo(nil), OutputRewriter(rewriter), StripSchedulingMarkers(true))
+	report, err := parser.ParseWithStderr(strings.NewReader(stdout), strings.NewReader(stderr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := report.Packages[0].Tests[0]
+	if diff := cmp.Diff([]string{"stdout ***\n"}, test.Output); diff != "" {
+		t.Errorf("unexpected output, diff (-want, +got):\n%s\n", diff)
+	}
+	if diff := cmp.Diff([]string{"stderr ***\n"}, test.Stderr); diff != "" {
+		t.Errorf("unexpected stderr, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestParsePackageErrorsRewritesOutput(t *testing.T) {
+	input := `{"Action":"run","Package":"package/name","Test":"TestOne"}
+{"Action":"pass","Package":"package/name","Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":"package/name","Output":"PASS\n"}
+{"Action":"output","Package":"package/name","Output":"teardown failed: token=SECRET\n"}
+{"Action":"output","Package":"package/name","Output":"FAIL\tpackage/name\t0.010s\n"}
+{"Action":"fail","Package":"package/name","Elapsed":0.01}
+`
+	rewriter := func(s string) string { return strings.ReplaceAll(s, "SECRET", "***") }
+	report, err := NewParser(Echo(nil), OutputRewriter(rewriter)).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PackageError{{Phase: "teardown", Output: []string{"teardown failed: token=***\n"}}}
+	if diff := cmp.Diff(want, report.Packages[0].Errors); diff != "" {
+		t.Errorf("unexpected package errors, diff (-want, +got):\n%s\n", diff)
+	}
+}
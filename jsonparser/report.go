@@ -0,0 +1,526 @@
+package jsonparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sort sorts the packages in this report by name, and the tests and
+// benchmarks within each package by name. By default a parsed Report keeps
+// the order in which packages and tests completed; Sort can be used when a
+// stable, input independent order is preferred instead.
+func (r *Report) Sort() {
+	sort.SliceStable(r.Packages, func(i, j int) bool {
+		return r.Packages[i].Name < r.Packages[j].Name
+	})
+	for _, pkg := range r.Packages {
+		sort.SliceStable(pkg.Tests, func(i, j int) bool {
+			return pkg.Tests[i].Name < pkg.Tests[j].Name
+		})
+		sort.SliceStable(pkg.Benchmarks, func(i, j int) bool {
+			return pkg.Benchmarks[i].Name < pkg.Benchmarks[j].Name
+		})
+	}
+}
+
+// SlowestTests returns up to n tests across all packages in this report,
+// ordered by descending duration. Tests with equal durations are ordered by
+// name.
+func (r *Report) SlowestTests(n int) []*Test {
+	var tests []*Test
+	for _, pkg := range r.Packages {
+		tests = append(tests, pkg.Tests...)
+	}
+	sort.SliceStable(tests, func(i, j int) bool {
+		if tests[i].Duration != tests[j].Duration {
+			return tests[i].Duration > tests[j].Duration
+		}
+		return tests[i].Name < tests[j].Name
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(tests) {
+		tests = tests[:n]
+	}
+	return tests
+}
+
+// Dedup merges packages in this report that share the same name. The tests
+// and benchmarks of duplicate packages are appended to the first package with
+// that name and their durations are summed. This can happen when the output of
+// multiple go test runs was concatenated before parsing.
+func (r *Report) Dedup() {
+	var packages []*Package
+	byName := make(map[string]*Package)
+	for _, pkg := range r.Packages {
+		first, ok := byName[pkg.Name]
+		if !ok {
+			byName[pkg.Name] = pkg
+			packages = append(packages, pkg)
+			continue
+		}
+		first.Tests = append(first.Tests, pkg.Tests...)
+		first.Benchmarks = append(first.Benchmarks, pkg.Benchmarks...)
+		first.Warnings = append(first.Warnings, pkg.Warnings...)
+		first.Errors = append(first.Errors, pkg.Errors...)
+		first.Duration += pkg.Duration
+		first.Time += pkg.Time
+		if first.CoveragePct == "" {
+			first.CoveragePct = pkg.CoveragePct
+		}
+		first.NoTestsToRun = first.NoTestsToRun && pkg.NoTestsToRun
+		first.DataRace = first.DataRace || pkg.DataRace
+		if pkg.MaxParallel > first.MaxParallel {
+			first.MaxParallel = pkg.MaxParallel
+		}
+	}
+	r.Packages = packages
+}
+
+// Stats contains summary statistics of a Report.
+type Stats struct {
+	Total      int // total number of tests and package errors
+	Passed     int
+	Failed     int
+	Skipped    int
+	Errored    int // incomplete tests, tests that leaked goroutines and package errors
+	Benchmarks int
+
+	Duration time.Duration // sum of package durations
+}
+
+// Stats returns the summary statistics of this report.
+func (r *Report) Stats() Stats {
+	var s Stats
+	for _, pkg := range r.Packages {
+		ps := pkg.Stats()
+		s.Total += ps.Total
+		s.Passed += ps.Passed
+		s.Failed += ps.Failed
+		s.Skipped += ps.Skipped
+		s.Errored += ps.Errored
+		s.Benchmarks += ps.Benchmarks
+		s.Duration += ps.Duration
+	}
+	return s
+}
+
+// Stats returns the summary statistics of this package. Package errors are
+// counted as errored tests, like the testcases JUnitWriter writes for them.
+func (p *Package) Stats() Stats {
+	s := Stats{
+		Total:      len(p.Errors),
+		Errored:    len(p.Errors),
+		Benchmarks: len(p.Benchmarks),
+		Duration:   p.Duration,
+	}
+	for _, t := range p.Tests {
+		s.Total++
+		switch {
+		case t.Incomplete || t.LeakDetected:
+			s.Errored++
+		case t.Result == PASS:
+			s.Passed++
+		case t.Result == FAIL:
+			s.Failed++
+		case t.Result == SKIP:
+			s.Skipped++
+		}
+	}
+	return s
+}
+
+// CachedPackages returns the packages in this report whose results were served
+// from the go test cache.
+func (r *Report) CachedPackages() []*Package {
+	var cached []*Package
+	for _, pkg := range r.Packages {
+		if pkg.Cached {
+			cached = append(cached, pkg)
+		}
+	}
+	return cached
+}
+
+// Clone returns a deep copy of this report. Modifying the packages, tests,
+// benchmarks or output of the returned report does not affect the original.
+func (r *Report) Clone() *Report {
+	clone := &Report{Packages: make([]*Package, 0, len(r.Packages)), Timeout: r.Timeout, RaceEnabled: r.RaceEnabled}
+	for _, pkg := range r.Packages {
+		clone.Packages = append(clone.Packages, pkg.Clone())
+	}
+	return clone
+}
+
+// Clone returns a deep copy of this package.
+func (p *Package) Clone() *Package {
+	clone := *p
+	if p.Tests != nil {
+		clone.Tests = make([]*Test, 0, len(p.Tests))
+		for _, t := range p.Tests {
+			clone.Tests = append(clone.Tests, t.Clone())
+		}
+	}
+	if p.Benchmarks != nil {
+		clone.Benchmarks = make([]*Benchmark, 0, len(p.Benchmarks))
+		for _, b := range p.Benchmarks {
+			bc := *b
+			clone.Benchmarks = append(clone.Benchmarks, &bc)
+		}
+	}
+	if p.Warnings != nil {
+		clone.Warnings = append(make([]string, 0, len(p.Warnings)), p.Warnings...)
+	}
+	if p.Errors != nil {
+		clone.Errors = make([]PackageError, 0, len(p.Errors))
+		for _, e := range p.Errors {
+			e.Output = append([]string(nil), e.Output...)
+			clone.Errors = append(clone.Errors, e)
+		}
+	}
+	return &clone
+}
+
+// Clone returns a deep copy of this test.
+func (t *Test) Clone() *Test {
+	clone := *t
+	if t.Output != nil {
+		clone.Output = append(make([]string, 0, len(t.Output)), t.Output...)
+	}
+	if t.Events != nil {
+		clone.Events = append(make([]TestEvent, 0, len(t.Events)), t.Events...)
+	}
+	if t.Stderr != nil {
+		clone.Stderr = append(make([]string, 0, len(t.Stderr)), t.Stderr...)
+	}
+	if t.Attempts != nil {
+		clone.Attempts = append(make([]Result, 0, len(t.Attempts)), t.Attempts...)
+	}
+	return &clone
+}
+
+// Merge adds the packages of other to this report. Packages that share the
+// same name are merged into a single package, see Dedup. If this report has
+// no Timeout, the Timeout of other is used.
+func (r *Report) Merge(other *Report) {
+	if r.Timeout == 0 {
+		r.Timeout = other.Timeout
+	}
+	r.RaceEnabled = r.RaceEnabled || other.RaceEnabled
+	r.Packages = append(r.Packages, other.Packages...)
+	r.Dedup()
+}
+
+// RenamePackages replaces the name of every package in this report by the
+// result of calling fn with its current name. The Package field of the tests
+// in each package is updated to match the new package name.
+func (r *Report) RenamePackages(fn func(string) string) {
+	for _, pkg := range r.Packages {
+		pkg.Name = fn(pkg.Name)
+		for _, t := range pkg.Tests {
+			t.Package = pkg.Name
+		}
+	}
+}
+
+// Walk calls visit for every package and test in this report. For each
+// package visit is first called once with a nil test, followed by a call for
+// every test in that package.
+func (r *Report) Walk(visit func(pkg *Package, test *Test)) {
+	for _, pkg := range r.Packages {
+		visit(pkg, nil)
+		for _, t := range pkg.Tests {
+			visit(pkg, t)
+		}
+	}
+}
+
+// AddPackage returns the package with the given name in this report. If the
+// report does not contain such a package yet, a new package is added to the
+// end of the report and returned.
+func (r *Report) AddPackage(name string) *Package {
+	if pkg := findPackage(r.Packages, name); pkg != nil {
+		return pkg
+	}
+	pkg := NewPackage(name)
+	r.Packages = append(r.Packages, pkg)
+	return pkg
+}
+
+// AddTest adds a new test with the given name, result and duration to this
+// package and returns it.
+func (p *Package) AddTest(name string, result Result, d time.Duration) *Test {
+	t := NewTest(name, p.Name)
+	t.Result = result
+	t.Duration = d
+	p.Tests = append(p.Tests, t)
+	return t
+}
+
+// FoldSubtests merges all subtests in this package into their top-level test
+// and removes them from the package. The output of subtests is appended to the
+// output of their top-level test in the order the subtests appear, and the
+// top-level test is marked as failed or incomplete if any of its subtests
+// were. The duration of the top-level test is kept, since it already includes
+// the time spent in its subtests. Subtests whose top-level test is not in the
+// package are kept.
+func (p *Package) FoldSubtests() {
+	parents := make(map[string]*Test)
+	for _, t := range p.Tests {
+		if !strings.Contains(t.Name, "/") {
+			parents[t.Name] = t
+		}
+	}
+
+	tests := make([]*Test, 0, len(p.Tests))
+	for _, t := range p.Tests {
+		parent, ok := parents[parentName(t.Name)]
+		if !ok || parent == t {
+			tests = append(tests, t)
+			continue
+		}
+		parent.Output = append(parent.Output, t.Output...)
+		if t.Result == FAIL {
+			parent.Result = FAIL
+		}
+		parent.Incomplete = parent.Incomplete || t.Incomplete
+	}
+	p.Tests = tests
+}
+
+// GroupByPrefix splits this report into separate reports by the first depth
+// "/"-separated segments of the package names. For example with depth 1 the
+// packages "services/a" and "services/b" end up in a report with key
+// "services" and "libs/c" in a report with key "libs". Packages with fewer
+// segments than depth are grouped by their full name, and a depth less than
+// 1 groups every package by its full name. The returned reports share their
+// packages with this report.
+func (r *Report) GroupByPrefix(depth int) map[string]*Report {
+	groups := make(map[string]*Report)
+	for _, pkg := range r.Packages {
+		key := pkg.Name
+		if segments := strings.Split(pkg.Name, "/"); depth > 0 && len(segments) > depth {
+			key = strings.Join(segments[:depth], "/")
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &Report{Packages: make([]*Package, 0), Timeout: r.Timeout}
+			groups[key] = group
+		}
+		group.Packages = append(group.Packages, pkg)
+	}
+	return groups
+}
+
+// TestsSlowerThan returns the tests in this report that took longer than d, in
+// the order they appear in the report.
+func (r *Report) TestsSlowerThan(d time.Duration) []*Test {
+	var slow []*Test
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			if t.Duration > d {
+				slow = append(slow, t)
+			}
+		}
+	}
+	return slow
+}
+
+// RemoveEmptyPackages removes all packages without any tests, benchmarks or
+// package errors from this report. If keepNoTestFiles is true, packages for
+// which go test reported that they have no test files are kept.
+func (r *Report) RemoveEmptyPackages(keepNoTestFiles bool) {
+	packages := make([]*Package, 0, len(r.Packages))
+	for _, pkg := range r.Packages {
+		if len(pkg.Tests) > 0 || len(pkg.Benchmarks) > 0 || len(pkg.Errors) > 0 || (keepNoTestFiles && pkg.NoTestFiles) {
+			packages = append(packages, pkg)
+		}
+	}
+	r.Packages = packages
+}
+
+// ID returns a stable identifier for this test, derived from its package and
+// full name. Tests with the same package and name, e.g. the same test in
+// different runs, have the same ID.
+func (t *Test) ID() string {
+	sum := sha256.Sum256([]byte(t.Package + "\x00" + t.Name))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CountByResult returns the number of tests in this report for every result.
+// Results that do not occur in the report are not included. Unlike Stats,
+// incomplete tests are counted by their result, which is FAIL.
+func (r *Report) CountByResult() map[Result]int {
+	counts := make(map[Result]int)
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			counts[t.Result]++
+		}
+	}
+	return counts
+}
+
+// FilterByPackage returns a new report containing only the packages in this
+// report for which match returns true. The returned report shares its
+// packages with this report.
+func (r *Report) FilterByPackage(match func(string) bool) *Report {
+	filtered := &Report{Packages: make([]*Package, 0), Timeout: r.Timeout, RaceEnabled: r.RaceEnabled}
+	for _, pkg := range r.Packages {
+		if match(pkg.Name) {
+			filtered.Packages = append(filtered.Packages, pkg)
+		}
+	}
+	return filtered
+}
+
+// ResultHistory returns the results of all attempts of this test in the order
+// they completed, see Test.Attempts. If no attempts were tracked, the result
+// of this test is returned as its only attempt.
+func (t *Test) ResultHistory() []Result {
+	if len(t.Attempts) == 0 {
+		return []Result{t.Result}
+	}
+	return append([]Result(nil), t.Attempts...)
+}
+
+// FlakyTests returns the tests in this report whose attempts did not all have
+// the same result, e.g. tests that failed twice and then passed, in the order
+// they appear in the report. Attempts are only known when the report was
+// parsed with the TrackAttempts option.
+func (r *Report) FlakyTests() []*Test {
+	var flaky []*Test
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			history := t.ResultHistory()
+			for _, result := range history[1:] {
+				if result != history[0] {
+					flaky = append(flaky, t)
+					break
+				}
+			}
+		}
+	}
+	return flaky
+}
+
+// DurationHistogram counts the tests in this report by duration. Every test
+// is counted in the smallest bucket whose upper bound is at least the
+// duration of the test, and the returned map contains the count for every
+// bucket by its upper bound. Buckets do not need to be sorted. Tests that
+// took longer than the largest bucket are not counted; use a bucket of
+// math.MaxInt64 to count them as well.
+func (r *Report) DurationHistogram(buckets []time.Duration) map[time.Duration]int {
+	bounds := append([]time.Duration(nil), buckets...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	histogram := make(map[time.Duration]int, len(bounds))
+	for _, b := range bounds {
+		histogram[b] = 0
+	}
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			if i := sort.Search(len(bounds), func(i int) bool { return bounds[i] >= t.Duration }); i < len(bounds) {
+				histogram[bounds[i]]++
+			}
+		}
+	}
+	return histogram
+}
+
+// FailureSummary is a short description of a failed test, see
+// Report.FailureSummaries.
+type FailureSummary struct {
+	Package string
+	Test    string
+
+	// Message is the first line of output of the test that explains the
+	// failure, without leading and trailing whitespace. It is empty if no
+	// such line was found.
+	Message string
+}
+
+// FailureSummaries returns a summary of every failed test in this report, in
+// the order they appear in the report. The message of each summary is the
+// first line of output that was logged with a file and line location, e.g.
+// "main_test.go:12: got 1, want 2", or otherwise the first line starting with
+// "panic:".
+func (r *Report) FailureSummaries() []FailureSummary {
+	var summaries []FailureSummary
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			if t.Result != FAIL {
+				continue
+			}
+			summaries = append(summaries, FailureSummary{
+				Package: pkg.Name,
+				Test:    t.Name,
+				Message: failureMessage(t.Output),
+			})
+		}
+	}
+	return summaries
+}
+
+// failureMessage returns the first line in output that explains a failure.
+func failureMessage(output []string) string {
+	var panicLine string
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		if regexLocation.MatchString(line) {
+			return line
+		}
+		if panicLine == "" && strings.HasPrefix(line, "panic:") {
+			panicLine = line
+		}
+	}
+	return panicLine
+}
+
+// PruneOutputForPassing removes the output of all passing tests in this
+// report, while keeping the output of failed and skipped tests. This can be
+// used to reduce the size of reports that are archived.
+func (r *Report) PruneOutputForPassing() {
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			if t.Result == PASS {
+				t.Output = make([]string, 0)
+			}
+		}
+	}
+}
+
+// TopPackagesByFailures returns up to n packages in this report that contain
+// failed tests, ordered by descending number of failed tests. Packages with
+// the same number of failed tests are ordered by name. As with Failures,
+// incomplete tests are counted as failed.
+func (r *Report) TopPackagesByFailures(n int) []*Package {
+	failures := make(map[*Package]int)
+	var packages []*Package
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			if t.Result == FAIL {
+				failures[pkg]++
+			}
+		}
+		if failures[pkg] > 0 {
+			packages = append(packages, pkg)
+		}
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		if failures[packages[i]] != failures[packages[j]] {
+			return failures[packages[i]] > failures[packages[j]]
+		}
+		return packages[i].Name < packages[j].Name
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(packages) {
+		packages = packages[:n]
+	}
+	return packages
+}
@@ -0,0 +1,575 @@
+package jsonparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSlowestTests(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Tests: []*Test{
+			{Name: "TestA1", Duration: 10 * time.Millisecond},
+			{Name: "TestA2", Duration: 300 * time.Millisecond},
+		}},
+		{Name: "package/b", Tests: []*Test{
+			{Name: "TestB2", Duration: 50 * time.Millisecond},
+			{Name: "TestB1", Duration: 50 * time.Millisecond},
+			{Name: "TestB3", Duration: 1 * time.Second},
+		}},
+	}}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, nil},
+		{1, []string{"TestB3"}},
+		{3, []string{"TestB3", "TestA2", "TestB1"}},
+		{10, []string{"TestB3", "TestA2", "TestB1", "TestB2", "TestA1"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		for _, slow := range report.SlowestTests(test.n) {
+			got = append(got, slow.Name)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("SlowestTests(%d) incorrect, diff (-want, +got):\n%s\n", test.n, diff)
+		}
+	}
+}
+
+func TestDedup(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Duration: 1 * time.Second, Tests: []*Test{{Name: "TestA1"}}},
+		{Name: "package/b", Duration: 2 * time.Second, Tests: []*Test{{Name: "TestB1"}}},
+		{Name: "package/a", Duration: 3 * time.Second, Tests: []*Test{{Name: "TestA2"}}, Benchmarks: []*Benchmark{{Name: "BenchmarkA"}}, CoveragePct: "50.0"},
+	}}
+
+	want := &Report{Packages: []*Package{
+		{Name: "package/a", Duration: 4 * time.Second, Tests: []*Test{{Name: "TestA1"}, {Name: "TestA2"}}, Benchmarks: []*Benchmark{{Name: "BenchmarkA"}}, CoveragePct: "50.0"},
+		{Name: "package/b", Duration: 2 * time.Second, Tests: []*Test{{Name: "TestB1"}}},
+	}}
+
+	report.Dedup()
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("Dedup result incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestStats(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{
+			Name:     "package/a",
+			Duration: 1 * time.Second,
+			Tests: []*Test{
+				{Name: "TestPass", Result: PASS},
+				{Name: "TestFail", Result: FAIL},
+				{Name: "TestSkip", Result: SKIP},
+				{Name: "TestIncomplete", Result: FAIL, Incomplete: true},
+			},
+		},
+		{
+			Name:       "package/b",
+			Duration:   2 * time.Second,
+			Tests:      []*Test{{Name: "TestPass", Result: PASS}},
+			Benchmarks: []*Benchmark{{Name: "BenchmarkOne"}, {Name: "BenchmarkTwo"}},
+		},
+		{
+			Name:   "package/c",
+			Errors: []PackageError{{Phase: "setup"}},
+		},
+	}}
+
+	want := Stats{
+		Total:      6,
+		Passed:     2,
+		Failed:     1,
+		Skipped:    1,
+		Errored:    2,
+		Benchmarks: 2,
+		Duration:   3 * time.Second,
+	}
+	if diff := cmp.Diff(want, report.Stats()); diff != "" {
+		t.Errorf("Stats incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestCachedPackages(t *testing.T) {
+	input := `{"Action":"run","Package":"package/fresh","Test":"TestOne"}
+{"Action":"pass","Package":"package/fresh","Test":"TestOne","Elapsed":0}
+{"Action":"output","Package":"package/fresh","Output":"ok  \tpackage/fresh\t0.010s\n"}
+{"Action":"pass","Package":"package/fresh","Elapsed":0.01}
+{"Action":"output","Package":"package/cached","Output":"ok  \tpackage/cached\t(cached)\n"}
+{"Action":"pass","Package":"package/cached","Elapsed":0}
+`
+	report, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, pkg := range report.CachedPackages() {
+		got = append(got, pkg.Name)
+	}
+	if diff := cmp.Diff([]string{"package/cached"}, got); diff != "" {
+		t.Errorf("CachedPackages incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestClone(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name:       "package/name",
+		Duration:   time.Second,
+		Tests:      []*Test{{Name: "TestOne", Package: "package/name", Result: PASS, Output: []string{"output\n"}}},
+		Benchmarks: []*Benchmark{{Name: "BenchmarkOne", Bytes: 10}},
+	}}}
+	original := &Report{Packages: []*Package{{
+		Name:       "package/name",
+		Duration:   time.Second,
+		Tests:      []*Test{{Name: "TestOne", Package: "package/name", Result: PASS, Output: []string{"output\n"}}},
+		Benchmarks: []*Benchmark{{Name: "BenchmarkOne", Bytes: 10}},
+	}}}
+
+	clone := report.Clone()
+	if diff := cmp.Diff(report, clone); diff != "" {
+		t.Fatalf("Clone is not equal to the original, diff (-original, +clone):\n%s\n", diff)
+	}
+
+	clone.Packages[0].Name = "package/renamed"
+	clone.Packages[0].Tests[0].Result = FAIL
+	clone.Packages[0].Tests[0].Output[0] = "modified\n"
+	clone.Packages[0].Tests = append(clone.Packages[0].Tests, &Test{Name: "TestTwo"})
+	clone.Packages[0].Benchmarks[0].Bytes = 20
+	clone.Packages = append(clone.Packages, &Package{Name: "package/other"})
+
+	if diff := cmp.Diff(original, report); diff != "" {
+		t.Errorf("modifying the clone changed the original, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestRenamePackages(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "github.com/org/repo/billing", Tests: []*Test{
+			{Name: "TestInvoice", Package: "github.com/org/repo/billing"},
+			{Name: "TestRefund", Package: "github.com/org/repo/billing"},
+		}},
+		{Name: "github.com/org/repo/auth", Tests: []*Test{
+			{Name: "TestLogin", Package: "github.com/org/repo/auth"},
+		}},
+	}}
+
+	report.RenamePackages(func(name string) string {
+		return "area/" + strings.TrimPrefix(name, "github.com/org/repo/")
+	})
+
+	got := make(map[string]string)
+	for _, pkg := range report.Packages {
+		for _, test := range pkg.Tests {
+			if test.Package != pkg.Name {
+				t.Errorf("test %s has package %q, want %q", test.Name, test.Package, pkg.Name)
+			}
+			got[test.Name] = pkg.Name
+		}
+	}
+	want := map[string]string{
+		"TestInvoice": "area/billing",
+		"TestRefund":  "area/billing",
+		"TestLogin":   "area/auth",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RenamePackages incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Tests: []*Test{{Name: "TestA1"}, {Name: "TestA2"}}},
+		{Name: "package/empty"},
+		{Name: "package/b", Tests: []*Test{{Name: "TestB1"}}},
+	}}
+
+	var got []string
+	report.Walk(func(pkg *Package, test *Test) {
+		if test == nil {
+			got = append(got, pkg.Name)
+			return
+		}
+		got = append(got, pkg.Name+"."+test.Name)
+	})
+
+	want := []string{
+		"package/a",
+		"package/a.TestA1",
+		"package/a.TestA2",
+		"package/empty",
+		"package/b",
+		"package/b.TestB1",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Walk visited incorrect packages and tests, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestAddPackageAddTest(t *testing.T) {
+	report := &Report{}
+	report.AddPackage("package/a").AddTest("TestPass", PASS, time.Second)
+	report.AddPackage("package/b").AddTest("TestSkip", SKIP, 0)
+	failed := report.AddPackage("package/a").AddTest("TestFail", FAIL, 2*time.Second)
+	failed.Output = append(failed.Output, "main_test.go:5: unexpected <value>\n")
+
+	want := &Report{Packages: []*Package{
+		{
+			Name: "package/a",
+			Tests: []*Test{
+				{Name: "TestPass", Package: "package/a", Result: PASS, Duration: time.Second, Output: []string{}},
+				{Name: "TestFail", Package: "package/a", Result: FAIL, Duration: 2 * time.Second, Output: []string{"main_test.go:5: unexpected <value>\n"}},
+			},
+			Benchmarks: []*Benchmark{},
+		},
+		{
+			Name:       "package/b",
+			Tests:      []*Test{{Name: "TestSkip", Package: "package/b", Result: SKIP, Output: []string{}}},
+			Benchmarks: []*Benchmark{},
+		},
+	}}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("unexpected report, diff (-want, +got):\n%s\n", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{}).Write(&buf, report); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	var suites xmlTestsuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML written: %v\n%s", err, buf.String())
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Skipped != 1 {
+		t.Errorf("unexpected totals, got tests=%d failures=%d skipped=%d, want 3, 1, 1", suites.Tests, suites.Failures, suites.Skipped)
+	}
+}
+
+func TestFoldSubtests(t *testing.T) {
+	pkg := &Package{Name: "package/name", Tests: []*Test{
+		{Name: "TestParent/one", Result: PASS, Duration: time.Second, Output: []string{"one\n"}},
+		{Name: "TestParent/two", Result: FAIL, Duration: time.Second, Output: []string{"two\n"}},
+		{Name: "TestParent/two/nested", Result: PASS, Output: []string{"nested\n"}},
+		{Name: "TestParent", Result: PASS, Duration: 2 * time.Second, Output: []string{"parent\n"}},
+		{Name: "TestOrphan/sub", Result: PASS},
+		{Name: "TestSingle", Result: SKIP},
+	}}
+
+	pkg.FoldSubtests()
+
+	want := []*Test{
+		{Name: "TestParent", Result: FAIL, Duration: 2 * time.Second, Output: []string{"parent\n", "one\n", "two\n", "nested\n"}},
+		{Name: "TestOrphan/sub", Result: PASS},
+		{Name: "TestSingle", Result: SKIP},
+	}
+	if diff := cmp.Diff(want, pkg.Tests); diff != "" {
+		t.Errorf("FoldSubtests incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestGroupByPrefix(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "services/api/handlers"},
+		{Name: "libs/log"},
+		{Name: "services/worker"},
+		{Name: "tools"},
+	}}
+
+	tests := []struct {
+		depth int
+		want  map[string][]string
+	}{
+		{1, map[string][]string{
+			"services": {"services/api/handlers", "services/worker"},
+			"libs":     {"libs/log"},
+			"tools":    {"tools"},
+		}},
+		{2, map[string][]string{
+			"services/api":    {"services/api/handlers"},
+			"services/worker": {"services/worker"},
+			"libs/log":        {"libs/log"},
+			"tools":           {"tools"},
+		}},
+		{0, map[string][]string{
+			"services/api/handlers": {"services/api/handlers"},
+			"services/worker":       {"services/worker"},
+			"libs/log":              {"libs/log"},
+			"tools":                 {"tools"},
+		}},
+		{-1, map[string][]string{
+			"services/api/handlers": {"services/api/handlers"},
+			"services/worker":       {"services/worker"},
+			"libs/log":              {"libs/log"},
+			"tools":                 {"tools"},
+		}},
+	}
+
+	for _, test := range tests {
+		got := make(map[string][]string)
+		for key, group := range report.GroupByPrefix(test.depth) {
+			for _, pkg := range group.Packages {
+				got[key] = append(got[key], pkg.Name)
+			}
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("GroupByPrefix(%d) incorrect, diff (-want, +got):\n%s\n", test.depth, diff)
+		}
+	}
+}
+
+func TestTestsSlowerThan(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Tests: []*Test{
+			{Name: "TestFast", Duration: 10 * time.Millisecond},
+			{Name: "TestSlow", Duration: 3 * time.Second},
+		}},
+		{Name: "package/b", Tests: []*Test{
+			{Name: "TestFast", Duration: 20 * time.Millisecond},
+			{Name: "TestLimit", Duration: time.Second},
+		}},
+	}}
+
+	var got []string
+	for _, test := range report.TestsSlowerThan(time.Second) {
+		got = append(got, test.Name)
+	}
+	if diff := cmp.Diff([]string{"TestSlow"}, got); diff != "" {
+		t.Errorf("TestsSlowerThan incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestRemoveEmptyPackages(t *testing.T) {
+	input := `{"Action":"output","Package":"package/notests","Output":"?   \tpackage/notests\t[no test files]\n"}
+{"Action":"skip","Package":"package/notests","Elapsed":0}
+{"Action":"run","Package":"package/tests","Test":"TestOne"}
+{"Action":"pass","Package":"package/tests","Test":"TestOne","Elapsed":0}
+{"Action":"pass","Package":"package/tests","Elapsed":0}
+{"Action":"output","Package":"package/bench","Output":"BenchmarkOne-8 \t 1000\t 100 ns/op\n"}
+{"Action":"pass","Package":"package/bench","Elapsed":0}
+{"Action":"output","Package":"package/filtered","Output":"testing: warning: no tests to run\n"}
+{"Action":"pass","Package":"package/filtered","Elapsed":0}
+`
+	tests := []struct {
+		keepNoTestFiles bool
+		want            []string
+	}{
+		{false, []string{"package/tests", "package/bench"}},
+		{true, []string{"package/notests", "package/tests", "package/bench"}},
+	}
+
+	for _, test := range tests {
+		report, err := NewParser(Echo(nil)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report.RemoveEmptyPackages(test.keepNoTestFiles)
+
+		var got []string
+		for _, pkg := range report.Packages {
+			got = append(got, pkg.Name)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("RemoveEmptyPackages(%v) incorrect, diff (-want, +got):\n%s\n", test.keepNoTestFiles, diff)
+		}
+	}
+}
+
+func TestTestID(t *testing.T) {
+	a := &Test{Name: "TestOne/sub", Package: "package/a"}
+	if got, again := a.ID(), (&Test{Name: "TestOne/sub", Package: "package/a", Result: FAIL}).ID(); got != again {
+		t.Errorf("ID is not stable, got %q and %q", got, again)
+	}
+
+	others := []*Test{
+		{Name: "TestOne", Package: "package/a"},
+		{Name: "TestOne/sub", Package: "package/b"},
+		{Name: "sub", Package: "package/a/TestOne"},
+	}
+	for _, other := range others {
+		if a.ID() == other.ID() {
+			t.Errorf("ID of %s in %s equals ID of %s in %s: %q", a.Name, a.Package, other.Name, other.Package, a.ID())
+		}
+	}
+
+	tc := (JUnitWriter{TestIDProperty: true}).testsuites(&Report{Packages: []*Package{{Name: "package/a", Tests: []*Test{a}}}}).Suites[0].Testcases[0]
+	if diff := cmp.Diff(&[]xmlProperty{{Name: "id", Value: a.ID()}}, tc.Properties); diff != "" {
+		t.Errorf("unexpected testcase properties, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestCountByResult(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Tests: []*Test{{Result: PASS}, {Result: FAIL}, {Result: PASS}}},
+		{Name: "package/b", Tests: []*Test{{Result: PASS}, {Result: FAIL, Incomplete: true}}},
+	}}
+
+	want := map[Result]int{PASS: 3, FAIL: 2}
+	if diff := cmp.Diff(want, report.CountByResult()); diff != "" {
+		t.Errorf("CountByResult incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestFilterByPackage(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "example.com/services/api"},
+		{Name: "example.com/services/worker"},
+		{Name: "example.com/libs/log"},
+		{Name: "example.com/services/api/internal"},
+	}}
+
+	filtered := report.FilterByPackage(func(name string) bool {
+		ok, _ := path.Match("example.com/services/*", name)
+		return ok
+	})
+
+	var got []string
+	for _, pkg := range filtered.Packages {
+		got = append(got, pkg.Name)
+	}
+	if diff := cmp.Diff([]string{"example.com/services/api", "example.com/services/worker"}, got); diff != "" {
+		t.Errorf("FilterByPackage incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+	if len(report.Packages) != 4 {
+		t.Errorf("FilterByPackage modified the original report, got %d packages", len(report.Packages))
+	}
+}
+
+func TestFlakyTests(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/name", Tests: []*Test{
+			{Name: "TestFlaky", Result: PASS, Attempts: []Result{FAIL, FAIL, PASS}},
+			{Name: "TestStable", Result: PASS, Attempts: []Result{PASS, PASS}},
+			{Name: "TestUntracked", Result: FAIL},
+		}},
+	}}
+
+	var got []string
+	for _, test := range report.FlakyTests() {
+		got = append(got, test.Name)
+	}
+	if diff := cmp.Diff([]string{"TestFlaky"}, got); diff != "" {
+		t.Errorf("FlakyTests incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+	if diff := cmp.Diff([]Result{FAIL}, report.Packages[0].Tests[2].ResultHistory()); diff != "" {
+		t.Errorf("ResultHistory of untracked test incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestDurationHistogram(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Tests: []*Test{
+			{Duration: 5 * time.Millisecond},
+			{Duration: 10 * time.Millisecond},
+			{Duration: 50 * time.Millisecond},
+		}},
+		{Name: "package/b", Tests: []*Test{
+			{Duration: 0},
+			{Duration: 2 * time.Second},
+			{Duration: time.Minute},
+		}},
+	}}
+
+	got := report.DurationHistogram([]time.Duration{time.Second, 10 * time.Millisecond, 100 * time.Millisecond, 5 * time.Second})
+	want := map[time.Duration]int{
+		10 * time.Millisecond:  3,
+		100 * time.Millisecond: 1,
+		time.Second:            0,
+		5 * time.Second:        1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DurationHistogram incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestFailureSummaries(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/a", Tests: []*Test{
+			{Name: "TestPass", Result: PASS, Output: []string{"    a_test.go:5: logged\n"}},
+			{Name: "TestAssert", Result: FAIL, Output: []string{
+				"=== RUN   TestAssert\n",
+				"    a_test.go:10: got 1, want 2\n",
+				"    a_test.go:11: got 3, want 4\n",
+				"--- FAIL: TestAssert (0.00s)\n",
+			}},
+		}},
+		{Name: "package/b", Tests: []*Test{
+			{Name: "TestPanic", Result: FAIL, Output: []string{
+				"=== RUN   TestPanic\n",
+				"--- FAIL: TestPanic (0.00s)\n",
+				"panic: runtime error: index out of range [recovered]\n",
+			}},
+			{Name: "TestKilled", Result: FAIL, Incomplete: true, Output: []string{"=== RUN   TestKilled\n"}},
+		}},
+	}}
+
+	want := []FailureSummary{
+		{Package: "package/a", Test: "TestAssert", Message: "a_test.go:10: got 1, want 2"},
+		{Package: "package/b", Test: "TestPanic", Message: "panic: runtime error: index out of range [recovered]"},
+		{Package: "package/b", Test: "TestKilled"},
+	}
+	if diff := cmp.Diff(want, report.FailureSummaries()); diff != "" {
+		t.Errorf("FailureSummaries incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestPruneOutputForPassing(t *testing.T) {
+	report := &Report{Packages: []*Package{{Name: "package/name", Tests: []*Test{
+		{Name: "TestPass", Result: PASS, Output: []string{"=== RUN   TestPass\n", "--- PASS: TestPass (0.00s)\n"}},
+		{Name: "TestFail", Result: FAIL, Output: []string{"    main_test.go:10: failed\n"}},
+		{Name: "TestSkip", Result: SKIP, Output: []string{"    main_test.go:20: skipped\n"}},
+	}}}}
+
+	report.PruneOutputForPassing()
+
+	got := make(map[string][]string)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = test.Output
+	}
+	want := map[string][]string{
+		"TestPass": {},
+		"TestFail": {"    main_test.go:10: failed\n"},
+		"TestSkip": {"    main_test.go:20: skipped\n"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("PruneOutputForPassing incorrect, diff (-want, +got):\n%s\n", diff)
+	}
+}
+
+func TestTopPackagesByFailures(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{Name: "package/one", Tests: []*Test{{Result: FAIL}, {Result: PASS}}},
+		{Name: "package/passing", Tests: []*Test{{Result: PASS}}},
+		{Name: "package/three", Tests: []*Test{{Result: FAIL}, {Result: FAIL}, {Result: FAIL, Incomplete: true}}},
+		{Name: "package/b-two", Tests: []*Test{{Result: FAIL}, {Result: FAIL}}},
+		{Name: "package/a-two", Tests: []*Test{{Result: FAIL}, {Result: SKIP}, {Result: FAIL}}},
+	}}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{-1, nil},
+		{2, []string{"package/three", "package/a-two"}},
+		{10, []string{"package/three", "package/a-two", "package/b-two", "package/one"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, pkg := range report.TopPackagesByFailures(test.n) {
+			got = append(got, pkg.Name)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("TopPackagesByFailures(%d) incorrect, diff (-want, +got):\n%s\n", test.n, diff)
+		}
+	}
+}
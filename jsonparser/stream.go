@@ -0,0 +1,549 @@
+package jsonparser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knownActions contains the actions handled by this parser. Actions added in
+// newer versions of go test -json, such as attr, artifacts, build-output and
+// build-fail, are not handled and are rejected when StrictActions is enabled.
+var knownActions = map[string]bool{
+	"start":  true,
+	"run":    true,
+	"pause":  true,
+	"cont":   true,
+	"pass":   true,
+	"bench":  true,
+	"fail":   true,
+	"output": true,
+	"skip":   true,
+}
+
+// parseState contains the state of a single call to Parser.ParseStream. It
+// keeps track of all packages and tests that have not yet completed.
+type parseState struct {
+	p *Parser
+
+	// packages that have not completed yet, in the order they were created
+	packages []*Package
+
+	// tests that have not been added to a package yet, in the order they
+	// were created, and by testKey
+	tests  []*Test
+	byName map[string]*Test
+
+	// keep track of the order in which tests completed
+	completed []*Test
+	done      map[*Test]bool
+
+	// time of the first event seen for each package
+	started map[string]time.Time
+
+	// total number of packages and tests seen so far
+	numPackages int
+	numTests    int
+
+	// warnings and benchmarks for packages that have not completed yet, by
+	// package name
+	warnings   map[string][]string
+	benchmarks map[string][]*Benchmark
+
+	// packages whose output contained a race indicator
+	races map[string]bool
+
+	// output that does not belong to any test, by package name
+	output map[string][]string
+}
+
+func newParseState(p *Parser) *parseState {
+	return &parseState{
+		p:          p,
+		byName:     make(map[string]*Test),
+		done:       make(map[*Test]bool),
+		started:    make(map[string]time.Time),
+		warnings:   make(map[string][]string),
+		benchmarks: make(map[string][]*Benchmark),
+		races:      make(map[string]bool),
+		output:     make(map[string][]string),
+	}
+}
+
+// handle processes a single event. If the event completed a package, the
+// completed package is returned. An error is returned if the event caused
+// one of the configured limits to be exceeded, or if it contained an unknown
+// action while StrictActions is enabled.
+func (s *parseState) handle(lineoutput LineOutput) (*Package, error) {
+	if s.p.strictActions && !knownActions[lineoutput.Action] {
+		return nil, fmt.Errorf("unknown action: %q", lineoutput.Action)
+	}
+
+	// When the package name is inferred, events without a package are
+	// collected in a package without a name, which is named once it
+	// completes.
+	if lineoutput.Package == "" && !s.p.inferPackageName {
+		lineoutput.Package = s.p.packageName
+	}
+
+	if _, ok := s.started[lineoutput.Package]; !ok {
+		s.numPackages++
+		if max := s.p.maxPackages; max > 0 && s.numPackages > max {
+			return nil, fmt.Errorf("maximum number of packages exceeded: %d", max)
+		}
+		s.started[lineoutput.Package] = lineoutput.Time
+	}
+
+	if lineoutput.Action == "output" && isRaceIndicator(lineoutput.Output) {
+		s.races[lineoutput.Package] = true
+	}
+
+	if lineoutput.Test == "" {
+		return s.handlePackage(lineoutput)
+	}
+	return nil, s.handleTest(lineoutput)
+}
+
+func (s *parseState) handlePackage(lineoutput LineOutput) (*Package, error) {
+	pkg := findPackage(s.packages, lineoutput.Package)
+	if pkg == nil {
+		pkg = s.newPackage(lineoutput.Package)
+		s.packages = append(s.packages, pkg)
+	}
+
+	switch lineoutput.Action {
+	case "output":
+		if output := s.p.storedOutput(lineoutput.Output); output != "" {
+			s.output[pkg.Name] = append(s.output[pkg.Name], output)
+		}
+		s.parseBenchmark(lineoutput)
+
+		// Older versions of go test do not attribute benchmark output to
+		// the benchmark, in which case its status line is the only
+		// indication that a benchmark was skipped or failed.
+		if matches := regexBenchmarkStatus.FindStringSubmatch(strings.TrimSpace(lineoutput.Output)); matches != nil && matches[1] != "BENCH" {
+			s.benchmarkResult(pkg.Name, matches[2], parseResult(strings.ToLower(matches[1])))
+		}
+
+		if strings.TrimSpace(lineoutput.Output) == "testing: warning: no tests to run" {
+			pkg.NoTestsToRun = true
+		}
+		if matches := regexNoTestFiles.FindStringSubmatch(strings.TrimSpace(lineoutput.Output)); matches != nil && matches[1] == pkg.Name {
+			pkg.NoTestFiles = true
+		}
+
+		// Coverage always belongs to the package that is being tested,
+		// even when it was measured for other packages using -coverpkg.
+		if matches := regexCoverage.FindStringSubmatch(lineoutput.Output); matches != nil {
+			pkg.CoveragePct = matches[1]
+		}
+
+		// The summary line is only used as a fallback for when the
+		// terminal action of the package has no elapsed time.
+		if matches := regexSummary.FindStringSubmatch(strings.TrimSpace(lineoutput.Output)); matches != nil && matches[1] == pkg.Name {
+			if matches[3] != "" {
+				pkg.Cached = true
+				pkg.Duration = 0
+			} else {
+				pkg.Duration = parseSeconds(matches[2])
+			}
+		}
+	case "pass", "fail", "skip":
+		if d, ok := elapsed(lineoutput.Elapsed); !ok {
+			s.warn(pkg.Name, "invalid elapsed time %v for package %s", lineoutput.Elapsed, pkg.Name)
+		} else if d > 0 {
+			pkg.Duration = d
+		}
+		output := s.output[pkg.Name]
+		s.removePackage(pkg)
+		s.finishPackage(pkg)
+		if lineoutput.Action == "fail" {
+			packageError(pkg, output)
+		}
+		return pkg, nil
+	}
+	return nil, nil
+}
+
+func (s *parseState) handleTest(lineoutput LineOutput) error {
+	t := s.findTest(lineoutput.Test, lineoutput.Package)
+	if t == nil {
+		s.numTests++
+		if max := s.p.maxTests; max > 0 && s.numTests > max {
+			return fmt.Errorf("maximum number of tests exceeded: %d", max)
+		}
+		t = &Test{
+			Name:    lineoutput.Test,
+			Package: lineoutput.Package,
+			Result:  FAIL,
+			Output:  make([]string, 0),
+		}
+		s.tests = append(s.tests, t)
+		s.byName[testKey(t.Package, t.Name)] = t
+	}
+
+	switch lineoutput.Action {
+	case "run":
+		t.Timestamp = lineoutput.Time
+		t.Start = lineoutput.Time
+	case "output":
+		if output := s.p.storedOutput(lineoutput.Output); output != "" {
+			t.Output = append(t.Output, output)
+		}
+		if strings.Contains(lineoutput.Output, "found unexpected goroutines") {
+			t.LeakDetected = true
+		}
+		s.parseBenchmark(lineoutput)
+		if matches := regexFuzzCorpus.FindStringSubmatch(lineoutput.Output); matches != nil {
+			t.FuzzCorpus = matches[1]
+		}
+		if marker := s.p.ownerMarker; marker != "" {
+			if owner, ok := parseOwner(marker, lineoutput.Output); ok {
+				t.Owner = owner
+			}
+		}
+		if s.p.trackEvents {
+			s.trackEvent(lineoutput)
+		}
+	case "pass", "fail", "skip", "bench":
+		t.Result = parseResult(lineoutput.Action)
+		if s.p.trackAttempts {
+			t.Attempts = append(t.Attempts, t.Result)
+		}
+		d, ok := elapsed(lineoutput.Elapsed)
+		if !ok {
+			s.warn(t.Package, "invalid elapsed time %v for test %s", lineoutput.Elapsed, t.Name)
+		}
+		t.Duration = d
+		t.End = lineoutput.Time
+		if t.End.IsZero() && !t.Start.IsZero() {
+			t.End = t.Start.Add(d)
+		}
+		if !s.done[t] {
+			s.done[t] = true
+			s.completed = append(s.completed, t)
+		}
+		if t.Result != PASS && strings.HasPrefix(t.Name, "Benchmark") {
+			s.benchmarkResult(t.Package, t.Name, t.Result)
+		}
+		s.p.writeProgress(t.Result)
+	}
+	return nil
+}
+
+// trackEvent records the scheduling marker in the output of lineoutput, if
+// any, as an event of the test it refers to. The marker contains the name of
+// the test, which is not necessarily the test the output was attributed to.
+// Markers do not create tests, they are ignored if the test they refer to
+// does not exist.
+func (s *parseState) trackEvent(lineoutput LineOutput) {
+	// The marker is followed by padding and the name of the test, which
+	// may itself contain whitespace.
+	line := strings.TrimRight(lineoutput.Output, "\r\n")
+	if !strings.HasPrefix(line, "=== ") {
+		return
+	}
+	marker := strings.SplitN(line[len("=== "):], " ", 2)
+	if len(marker) != 2 {
+		return
+	}
+	name := strings.TrimLeft(marker[1], " ")
+
+	var action string
+	switch marker[0] {
+	case "RUN":
+		action = "run"
+	case "PAUSE":
+		action = "pause"
+	case "CONT":
+		action = "cont"
+	default:
+		return
+	}
+
+	if t := s.findTest(name, lineoutput.Package); t != nil {
+		t.Events = append(t.Events, TestEvent{Action: action, Time: lineoutput.Time})
+	}
+}
+
+// parseBenchmark adds a benchmark to the package of lineoutput if its output
+// contains a benchmark result line. Depending on the version of go test
+// these lines are attributed to either the benchmark or the package.
+func (s *parseState) parseBenchmark(lineoutput LineOutput) {
+	matches := regexBenchmarkResult.FindStringSubmatch(strings.TrimSpace(lineoutput.Output))
+	if matches == nil {
+		return
+	}
+	nsPerOp, _ := strconv.ParseFloat(matches[2], 64)
+	bytes, _ := strconv.Atoi(matches[3])
+	allocs, _ := strconv.Atoi(matches[4])
+	s.benchmarks[lineoutput.Package] = append(s.benchmarks[lineoutput.Package], &Benchmark{
+		Name:     matches[1],
+		Duration: time.Duration(nsPerOp),
+		Bytes:    bytes,
+		Allocs:   allocs,
+	})
+}
+
+// benchmarkResult sets the result of the last benchmark with the given name
+// in package pkg. If no results were reported for the benchmark, a benchmark
+// without results is added.
+func (s *parseState) benchmarkResult(pkg, name string, result Result) {
+	benchmarks := s.benchmarks[pkg]
+	for i := len(benchmarks) - 1; i >= 0; i-- {
+		if benchmarks[i].Name == name {
+			benchmarks[i].Result = result
+			benchmarks[i].Skipped = result == SKIP
+			return
+		}
+	}
+	s.benchmarks[pkg] = append(benchmarks, &Benchmark{Name: name, Result: result, Skipped: result == SKIP})
+}
+
+// warn records a warning for the package with the given name.
+func (s *parseState) warn(pkg, format string, args ...interface{}) {
+	s.warnings[pkg] = append(s.warnings[pkg], fmt.Sprintf(format, args...))
+}
+
+// flush returns all packages that have not completed yet, including packages
+// for tests that did not belong to any known package.
+func (s *parseState) flush() []*Package {
+	packages := s.packages
+	s.packages = nil
+	for _, t := range s.ordered() {
+		if findPackage(packages, t.Package) == nil {
+			packages = append(packages, s.newPackage(t.Package))
+		}
+	}
+	for _, pkg := range packages {
+		s.finishPackage(pkg)
+	}
+	return packages
+}
+
+// finishPackage moves all tests that belong to pkg into pkg.
+func (s *parseState) finishPackage(pkg *Package) {
+	orphaned := s.orphanedSubtests(pkg.Name)
+
+	var remaining, tests []*Test
+	for _, t := range s.ordered() {
+		if t.Package != pkg.Name {
+			remaining = append(remaining, t)
+			continue
+		}
+		tests = append(tests, t)
+		delete(s.byName, testKey(t.Package, t.Name))
+		switch {
+		case orphaned[t]:
+			t.Orphaned = true
+			if s.p.skipOrphanedSubtests {
+				t.Result = SKIP
+			} else {
+				t.Incomplete = true
+			}
+		case !s.done[t]:
+			t.Incomplete = s.p.markIncomplete
+		}
+		if timeout := s.p.timeout; timeout > 0 {
+			limit := timeout / 10 * 9
+			t.LikelyTimedOut = t.Duration >= limit || (!s.done[t] && pkg.Duration >= limit)
+		}
+		if t.Result == FAIL {
+			t.Fatal = isFatal(t.Output)
+			if s.p.inferAssertions {
+				t.Assertions = countAssertions(t.Output)
+			}
+		}
+		pkg.Tests = append(pkg.Tests, t)
+		delete(s.done, t)
+	}
+
+	if s.p.propagateFailures {
+		propagateFailures(tests)
+	}
+	pkg.MaxParallel = maxParallel(pkg.Tests)
+
+	pkg.Warnings = append(pkg.Warnings, s.warnings[pkg.Name]...)
+	delete(s.warnings, pkg.Name)
+	pkg.Benchmarks = append(pkg.Benchmarks, s.benchmarks[pkg.Name]...)
+	delete(s.benchmarks, pkg.Name)
+	pkg.DataRace = pkg.DataRace || s.races[pkg.Name]
+	delete(s.races, pkg.Name)
+	delete(s.output, pkg.Name)
+
+	if pkg.Name == "" && s.p.inferPackageName {
+		pkg.Name = inferPackageName(pkg.Tests)
+		if pkg.Name == "" {
+			pkg.Name = s.p.packageName
+		}
+		for _, t := range pkg.Tests {
+			t.Package = pkg.Name
+		}
+	}
+
+	s.tests = remaining
+	s.completed = s.completed[:0]
+	for _, t := range remaining {
+		if s.done[t] {
+			s.completed = append(s.completed, t)
+		}
+	}
+}
+
+// packageError adds a PackageError with the given package output to the
+// failed package pkg if none of its tests failed, see PackageError.
+func packageError(pkg *Package, output []string) {
+	for _, t := range pkg.Tests {
+		if t.Result == FAIL {
+			return
+		}
+	}
+	phase := "setup"
+	if len(pkg.Tests) > 0 {
+		phase = "teardown"
+	}
+	pkg.Errors = append(pkg.Errors, PackageError{Phase: phase, Output: stripSummaryLines(output)})
+}
+
+// orphanedSubtests returns the subtests in package pkg that did not complete
+// while their parent test completed without failing.
+func (s *parseState) orphanedSubtests(pkg string) map[*Test]bool {
+	orphaned := make(map[*Test]bool)
+	for _, t := range s.tests {
+		i := strings.LastIndex(t.Name, "/")
+		if t.Package != pkg || i < 0 || s.done[t] {
+			continue
+		}
+		if parent := s.findTest(t.Name[:i], pkg); parent != nil && s.done[parent] && parent.Result != FAIL {
+			orphaned[t] = true
+		}
+	}
+	return orphaned
+}
+
+// propagateFailures marks every test in tests that has a failed subtest in
+// tests as failed. Orphaned subtests are not taken into account, since their
+// parent did not fail.
+func propagateFailures(tests []*Test) {
+	byName := make(map[string]*Test, len(tests))
+	for _, t := range tests {
+		byName[t.Name] = t
+	}
+	for _, t := range tests {
+		if t.Result != FAIL || t.Orphaned {
+			continue
+		}
+		name := t.Name
+		for i := strings.LastIndex(name, "/"); i >= 0; i = strings.LastIndex(name, "/") {
+			name = name[:i]
+			if parent, ok := byName[name]; ok {
+				parent.Result = FAIL
+			}
+		}
+	}
+}
+
+// maxParallel returns the maximum number of tests without subtests in tests
+// whose Start to End time windows overlap.
+func maxParallel(tests []*Test) int {
+	parents := make(map[string]bool)
+	for _, t := range tests {
+		if i := strings.LastIndex(t.Name, "/"); i >= 0 {
+			parents[t.Package+"\x00"+t.Name[:i]] = true
+		}
+	}
+
+	type boundary struct {
+		time  time.Time
+		delta int
+	}
+	var boundaries []boundary
+	for _, t := range tests {
+		if t.Start.IsZero() || t.End.IsZero() || parents[t.Package+"\x00"+t.Name] {
+			continue
+		}
+		boundaries = append(boundaries, boundary{t.Start, 1}, boundary{t.End, -1})
+	}
+	// At equal times tests that end are processed first, so that a test
+	// starting exactly when another one ends does not count as overlapping.
+	sort.Slice(boundaries, func(i, j int) bool {
+		if !boundaries[i].time.Equal(boundaries[j].time) {
+			return boundaries[i].time.Before(boundaries[j].time)
+		}
+		return boundaries[i].delta < boundaries[j].delta
+	})
+
+	running, max := 0, 0
+	for _, b := range boundaries {
+		running += b.delta
+		if running > max {
+			max = running
+		}
+	}
+	return max
+}
+
+// ordered returns the tests that have not been added to a package yet. Tests
+// are returned in the order they completed, followed by any tests that have
+// not completed in the order they were created.
+func (s *parseState) ordered() []*Test {
+	tests := append([]*Test(nil), s.completed...)
+	for _, t := range s.tests {
+		if !s.done[t] {
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
+
+func (s *parseState) newPackage(name string) *Package {
+	return &Package{
+		Name:      name,
+		Tests:     make([]*Test, 0),
+		Timestamp: s.started[name],
+	}
+}
+
+func (s *parseState) removePackage(pkg *Package) {
+	for i, p := range s.packages {
+		if p == pkg {
+			s.packages = append(s.packages[:i], s.packages[i+1:]...)
+			return
+		}
+	}
+}
+
+// elapsed converts the given number of seconds into a duration. If seconds
+// is NaN, infinite, negative or too large to be represented as a duration, 0
+// is returned and ok is false.
+func elapsed(seconds float32) (d time.Duration, ok bool) {
+	f := float64(seconds) * float64(time.Second)
+	if math.IsNaN(f) || f < 0 || f > math.MaxInt64 {
+		return 0, false
+	}
+	return time.Duration(f), true
+}
+
+// findTest returns the test with the given name in package pkg that has not
+// been added to a package yet, or nil if there is no such test.
+func (s *parseState) findTest(name, pkg string) *Test {
+	return s.byName[testKey(pkg, name)]
+}
+
+// testKey returns the key of the test with the given package and name in
+// parseState.byName.
+func testKey(pkg, name string) string {
+	return pkg + "\x00" + name
+}
+
+func findPackage(packages []*Package, name string) *Package {
+	for i := len(packages) - 1; i >= 0; i-- {
+		if packages[i].Name == name {
+			return packages[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package jsonparser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// TextSummary writes a human-readable summary of report to w. For every
+// package a line with its status, duration, test counts and coverage is
+// written, followed by the names of its failed tests and the phases of its
+// package errors. The summary ends with a line containing the totals of all
+// packages, preceded by a note if the race detector was enabled.
+func TextSummary(report *Report, w io.Writer) error {
+	var buf bytes.Buffer
+	for _, pkg := range report.Packages {
+		s := pkg.Stats()
+		status := "ok"
+		if s.Failed > 0 || s.Errored > 0 {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&buf, "%-4s %s\t%ss\t%s", status, pkg.Name, formatDuration(s.Duration), formatCounts(s))
+		if pkg.CoveragePct != "" {
+			fmt.Fprintf(&buf, ", coverage %s%%", pkg.CoveragePct)
+		}
+		buf.WriteString("\n")
+
+		for _, t := range pkg.Tests {
+			if t.Result == FAIL {
+				fmt.Fprintf(&buf, "    --- FAIL: %s\n", t.Name)
+			}
+		}
+		for _, e := range pkg.Errors {
+			fmt.Fprintf(&buf, "    --- FAIL: %s (package error)\n", e.Phase)
+		}
+	}
+
+	if report.RaceEnabled {
+		buf.WriteString("race detector enabled, durations include its overhead\n")
+	}
+
+	s := report.Stats()
+	fmt.Fprintf(&buf, "total: %d tests in %ss\t%s\n", s.Total, formatDuration(s.Duration), formatCounts(s))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func formatCounts(s Stats) string {
+	counts := fmt.Sprintf("%d passed, %d failed, %d skipped", s.Passed, s.Failed, s.Skipped)
+	if s.Errored > 0 {
+		counts += fmt.Sprintf(", %d errored", s.Errored)
+	}
+	return counts
+}
@@ -0,0 +1,84 @@
+package jsonparser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextSummary(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{
+			Name:        "package/pass",
+			Duration:    1500 * time.Millisecond,
+			CoveragePct: "82.5",
+			Tests: []*Test{
+				{Name: "TestOne", Result: PASS},
+				{Name: "TestTwo", Result: SKIP},
+			},
+		},
+		{
+			Name:     "package/fail",
+			Duration: 250 * time.Millisecond,
+			Tests: []*Test{
+				{Name: "TestOne", Result: PASS},
+				{Name: "TestBroken", Result: FAIL},
+			},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := TextSummary(report, &buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	want := []string{
+		"ok   package/pass\t1.500s\t1 passed, 0 failed, 1 skipped, coverage 82.5%\n",
+		"FAIL package/fail\t0.250s\t1 passed, 1 failed, 0 skipped\n",
+		"    --- FAIL: TestBroken\n",
+		"total: 4 tests in 1.750s\t2 passed, 1 failed, 1 skipped\n",
+	}
+	for _, line := range want {
+		if !strings.Contains(got, line) {
+			t.Errorf("summary does not contain %q, got:\n%s", line, got)
+		}
+	}
+	if !strings.HasSuffix(got, want[len(want)-1]) {
+		t.Errorf("summary does not end with the totals line, got:\n%s", got)
+	}
+}
+
+func TestTextSummaryPackageErrors(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{
+			Name:   "package/setup",
+			Errors: []PackageError{{Phase: "setup", Output: []string{"panic: setup failed\n"}}},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := TextSummary(report, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "FAIL package/setup\t0.000s\t0 passed, 0 failed, 0 skipped, 1 errored\n" +
+		"    --- FAIL: setup (package error)\n" +
+		"total: 1 tests in 0.000s\t0 passed, 0 failed, 0 skipped, 1 errored\n"
+	if got := buf.String(); got != want {
+		t.Errorf("TextSummary incorrect, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTextSummaryRaceEnabled(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := TextSummary(&Report{RaceEnabled: enabled}, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(buf.String(), "race detector enabled"); got != enabled {
+			t.Errorf("RaceEnabled=%v: unexpected summary:\n%s", enabled, buf.String())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package jsonparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// junitRequiredAttrs contains the attributes that are required for each
+// element of a JUnit XML report.
+var junitRequiredAttrs = map[string][]string{
+	"testsuite": {"name", "tests"},
+	"testcase":  {"name", "classname"},
+	"property":  {"name", "value"},
+}
+
+// junitParents contains the elements in which each element of a JUnit XML
+// report may appear. Elements that are not listed may appear anywhere, and
+// an empty parent stands for the root of the document.
+var junitParents = map[string][]string{
+	"testsuites": {""},
+	"testsuite":  {"", "testsuites", "testsuite"},
+	"properties": {"testsuite", "testcase"},
+	"property":   {"properties"},
+	"testcase":   {"testsuite"},
+	"skipped":    {"testcase"},
+	"error":      {"testcase"},
+	"failure":    {"testcase"},
+}
+
+// ValidateJUnitXML checks that r contains a well-formed JUnit XML report, as
+// written by JUnitWriter. The root element must be a testsuites or testsuite
+// element, required attributes such as the name of testsuites and testcases
+// must be present, and count and time attributes must be numbers. It does not
+// validate against a complete JUnit schema, since consumers of JUnit XML
+// disagree on which elements and attributes are allowed. The first problem
+// that was found is returned.
+func ValidateJUnitXML(r io.Reader) error {
+	dec := xml.NewDecoder(r)
+	var stack []string
+	root := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := tok.Name.Local
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			} else if root {
+				return fmt.Errorf("multiple root elements: <%s>", name)
+			}
+			if parents, ok := junitParents[name]; ok && !contains(parents, parent) {
+				if parent == "" {
+					return fmt.Errorf("unexpected root element <%s>", name)
+				}
+				return fmt.Errorf("unexpected <%s> in <%s>", name, parent)
+			} else if parent == "" && name != "testsuites" && name != "testsuite" {
+				return fmt.Errorf("unexpected root element <%s>", name)
+			}
+			if err := validateJUnitAttrs(name, tok.Attr); err != nil {
+				return err
+			}
+			stack = append(stack, name)
+			root = true
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if !root {
+		return fmt.Errorf("no root element found")
+	}
+	return nil
+}
+
+// validateJUnitAttrs checks the attributes of an element with the given name.
+func validateJUnitAttrs(name string, attrs []xml.Attr) error {
+	values := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		values[attr.Name.Local] = attr.Value
+	}
+	for _, required := range junitRequiredAttrs[name] {
+		if _, ok := values[required]; !ok {
+			return fmt.Errorf("<%s> is missing required attribute %q", name, required)
+		}
+	}
+	for _, attr := range []string{"tests", "failures", "errors", "skipped", "assertions", "retries"} {
+		if v, ok := values[attr]; ok {
+			if n, err := strconv.Atoi(v); err != nil || n < 0 {
+				return fmt.Errorf("<%s> has invalid %s attribute: %q", name, attr, v)
+			}
+		}
+	}
+	if v, ok := values["time"]; ok {
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("<%s> has invalid time attribute: %q", name, v)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
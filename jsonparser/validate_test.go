@@ -0,0 +1,38 @@
+package jsonparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateJUnitXML(t *testing.T) {
+	f, err := os.Open("testdata/gitlab-report.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := ValidateJUnitXML(f); err != nil {
+		t.Errorf("ValidateJUnitXML of a valid report returned error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"empty", ``, "no root element found"},
+		{"unclosed", `<testsuites><testsuite name="a" tests="0">`, "unexpected EOF"},
+		{"root", `<testcase name="a" classname="b"></testcase>`, "unexpected root element <testcase>"},
+		{"missing attribute", `<testsuites><testsuite name="a" tests="1"><testcase name="b"></testcase></testsuite></testsuites>`, `<testcase> is missing required attribute "classname"`},
+		{"invalid count", `<testsuite name="a" tests="one"></testsuite>`, `<testsuite> has invalid tests attribute: "one"`},
+		{"invalid time", `<testsuite name="a" tests="0" time="1s"></testsuite>`, `<testsuite> has invalid time attribute: "1s"`},
+		{"misplaced", `<testsuite name="a" tests="0"><failure message="x"></failure></testsuite>`, "unexpected <failure> in <testsuite>"},
+	}
+	for _, test := range tests {
+		err := ValidateJUnitXML(strings.NewReader(test.input))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ValidateJUnitXML(%s): got error %v, want error containing %q", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package jsonparser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// formatWriters contains the function that writes a report for every format
+// supported by WriteAll.
+var formatWriters = map[string]func(*Report, io.Writer) error{
+	"junit":      func(r *Report, w io.Writer) error { return JUnitWriter{}.Write(w, r) },
+	"xunit":      XUnitReportXML,
+	"checkstyle": CheckstyleReport,
+	"jsonl":      WritePackageJSONL,
+	"text":       TextSummary,
+}
+
+// WriteAll writes report in several formats at once. The keys of writers
+// select the format that is written to each writer: "junit" for JUnit XML
+// using the default JUnitWriter, "xunit" for xUnit XML, "checkstyle" for
+// checkstyle XML, "jsonl" for JSON Lines package summaries and "text" for a
+// plain text summary. A failure to write one format does not prevent the
+// others from being written; the errors of all formats are combined into the
+// returned error, which also reports unknown formats. Formats are written in
+// lexical order.
+func WriteAll(report *Report, writers map[string]io.Writer) error {
+	formats := make([]string, 0, len(writers))
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	var errs multiError
+	for _, format := range formats {
+		write, ok := formatWriters[format]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown format: %q", format))
+			continue
+		}
+		if err := write(report, writers[format]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", format, err))
+		}
+	}
+	return errs.errorOrNil()
+}
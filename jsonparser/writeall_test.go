@@ -0,0 +1,55 @@
+package jsonparser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriteAll(t *testing.T) {
+	report := &Report{Packages: []*Package{{
+		Name:  "package/name",
+		Tests: []*Test{{Name: "TestOne", Result: PASS}, {Name: "TestTwo", Result: FAIL}},
+	}}}
+
+	var junit, text bytes.Buffer
+	if err := WriteAll(report, map[string]io.Writer{"junit": &junit, "text": &text}); err != nil {
+		t.Fatalf("WriteAll error: %v", err)
+	}
+
+	var wantJUnit, wantText bytes.Buffer
+	if err := (JUnitWriter{}).Write(&wantJUnit, report); err != nil {
+		t.Fatal(err)
+	}
+	if err := TextSummary(report, &wantText); err != nil {
+		t.Fatal(err)
+	}
+	if junit.String() != wantJUnit.String() {
+		t.Errorf("unexpected junit output, got:\n%s\nwant:\n%s", junit.String(), wantJUnit.String())
+	}
+	if text.String() != wantText.String() {
+		t.Errorf("unexpected text output, got:\n%s\nwant:\n%s", text.String(), wantText.String())
+	}
+
+	var xunit bytes.Buffer
+	err := WriteAll(report, map[string]io.Writer{"junit": failingWriter{}, "nunit": &bytes.Buffer{}, "xunit": &xunit})
+	if err == nil {
+		t.Fatal("WriteAll did not return an error")
+	}
+	for _, want := range []string{"junit: disk full", `unknown format: "nunit"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("WriteAll error %q does not contain %q", err, want)
+		}
+	}
+	if xunit.Len() == 0 {
+		t.Error("WriteAll did not write xunit after an earlier format failed")
+	}
+}
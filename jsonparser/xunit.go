@@ -0,0 +1,132 @@
+package jsonparser
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type xunitAssemblies struct {
+	XMLName    xml.Name        `xml:"assemblies"`
+	Assemblies []xunitAssembly `xml:"assembly"`
+}
+
+type xunitAssembly struct {
+	Name          string `xml:"name,attr"`
+	TestFramework string `xml:"test-framework,attr"`
+	RunDate       string `xml:"run-date,attr,omitempty"`
+	RunTime       string `xml:"run-time,attr,omitempty"`
+	Total         int    `xml:"total,attr"`
+	Passed        int    `xml:"passed,attr"`
+	Failed        int    `xml:"failed,attr"`
+	Skipped       int    `xml:"skipped,attr"`
+	Time          string `xml:"time,attr"`
+	Errors        int    `xml:"errors,attr"`
+
+	Collection xunitCollection `xml:"collection"`
+}
+
+type xunitCollection struct {
+	Name    string      `xml:"name,attr"`
+	Total   int         `xml:"total,attr"`
+	Passed  int         `xml:"passed,attr"`
+	Failed  int         `xml:"failed,attr"`
+	Skipped int         `xml:"skipped,attr"`
+	Time    string      `xml:"time,attr"`
+	Tests   []xunitTest `xml:"test"`
+}
+
+type xunitTest struct {
+	Name   string `xml:"name,attr"`
+	Type   string `xml:"type,attr"`
+	Method string `xml:"method,attr"`
+	Time   string `xml:"time,attr"`
+	Result string `xml:"result,attr"`
+
+	Failure *xunitFailure `xml:"failure,omitempty"`
+	Reason  string        `xml:"reason,omitempty"`
+}
+
+type xunitFailure struct {
+	Message string `xml:"message"`
+}
+
+// XUnitReportXML writes report to w in the xUnit.net v2 XML format. Every
+// package is written as an assembly containing a single collection with the
+// tests of that package. The output of failed tests is written as their
+// failure message, and the output of skipped tests as the skip reason. Tests
+// without a result, see Test.Incomplete, are reported as failed, and package
+// errors are reported as failed tests named after their phase.
+func XUnitReportXML(report *Report, w io.Writer) error {
+	var doc xunitAssemblies
+	for _, pkg := range report.Packages {
+		s := pkg.Stats()
+		assembly := xunitAssembly{
+			Name:          pkg.Name,
+			TestFramework: "go test",
+			Total:         s.Total,
+			Passed:        s.Passed,
+			Failed:        s.Failed + s.Errored,
+			Skipped:       s.Skipped,
+			Time:          formatDuration(pkg.Duration),
+			Collection: xunitCollection{
+				Name:    pkg.Name,
+				Total:   s.Total,
+				Passed:  s.Passed,
+				Failed:  s.Failed + s.Errored,
+				Skipped: s.Skipped,
+				Time:    formatDuration(pkg.Duration),
+			},
+		}
+		if !pkg.Timestamp.IsZero() {
+			assembly.RunDate = pkg.Timestamp.Format("2006-01-02")
+			assembly.RunTime = pkg.Timestamp.Format("15:04:05")
+		}
+
+		for _, t := range pkg.Tests {
+			test := xunitTest{
+				Name:   pkg.Name + "." + t.Name,
+				Type:   pkg.Name,
+				Method: t.Name,
+				Time:   formatDuration(t.Duration),
+			}
+			output := strings.Join(t.Output, "")
+			switch {
+			case t.Incomplete || t.Result == FAIL:
+				test.Result = "Fail"
+				test.Failure = &xunitFailure{Message: output}
+			case t.Result == SKIP:
+				test.Result = "Skip"
+				test.Reason = output
+			default:
+				test.Result = "Pass"
+			}
+			assembly.Collection.Tests = append(assembly.Collection.Tests, test)
+		}
+		for _, e := range pkg.Errors {
+			assembly.Collection.Tests = append(assembly.Collection.Tests, xunitTest{
+				Name:    pkg.Name + "." + e.Phase,
+				Type:    pkg.Name,
+				Method:  e.Phase,
+				Time:    formatDuration(0),
+				Result:  "Fail",
+				Failure: &xunitFailure{Message: strings.Join(e.Output, "")},
+			})
+		}
+		doc.Assemblies = append(doc.Assemblies, assembly)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,42 @@
+package jsonparser
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestXUnitReportXML(t *testing.T) {
+	report := &Report{Packages: []*Package{
+		{
+			Name:      "package/a",
+			Duration:  1500 * time.Millisecond,
+			Timestamp: time.Date(2022, 1, 1, 12, 30, 0, 0, time.UTC),
+			Tests: []*Test{
+				{Name: "TestPass", Result: PASS, Duration: 100 * time.Millisecond},
+				{Name: "TestFail", Result: FAIL, Duration: 200 * time.Millisecond, Output: []string{"    a_test.go:10: got <nil>, want err\n"}},
+				{Name: "TestSkip", Result: SKIP, Output: []string{"    a_test.go:20: short mode\n"}},
+			},
+		},
+		{
+			Name:  "package/b",
+			Tests: []*Test{{Name: "TestKilled", Result: FAIL, Incomplete: true}},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := XUnitReportXML(report, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile("testdata/xunit-report.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), buf.String()); diff != "" {
+		t.Errorf("unexpected xUnit report, diff (-want, +got):\n%s\n", diff)
+	}
+}
@@ -18,16 +18,17 @@ var (
 )
 
 var (
-	noXMLHeader = flag.Bool("no-xml-header", false, "do not print xml header")
-	packageName = flag.String("package-name", "", "specify a default package `name` to use if output does not contain a package name")
-	setExitCode = flag.Bool("set-exit-code", false, "set exit code to 1 if tests failed")
-	version     = flag.Bool("version", false, "print version")
-	input       = flag.String("in", "", "read go test log from `file`")
-	output      = flag.String("out", "", "write XML report to `file`")
-	iocopy      = flag.Bool("iocopy", false, "copy input to stdout; can only be used in conjunction with -out")
-	properties  = make(keyValueFlag)
-	parser      = flag.String("parser", "gotest", "set input parser: gotest, gojson")
-	mode        = flag.String("subtest-mode", "", "set subtest `mode`: ignore-parent-results (subtest parents always pass), exclude-parents (subtest parents are excluded from the report)")
+	noXMLHeader   = flag.Bool("no-xml-header", false, "do not print xml header")
+	packageName   = flag.String("package-name", "", "specify a default package `name` to use if output does not contain a package name")
+	setExitCode   = flag.Bool("set-exit-code", false, "set exit code to 1 if tests failed")
+	version       = flag.Bool("version", false, "print version")
+	input         = flag.String("in", "", "read go test log from `file`")
+	output        = flag.String("out", "", "write XML report to `file`")
+	iocopy        = flag.Bool("iocopy", false, "copy input to stdout; can only be used in conjunction with -out")
+	properties    = make(keyValueFlag)
+	parser        = flag.String("parser", "gotest", "set input parser: gotest, gojson")
+	mode          = flag.String("subtest-mode", "", "set subtest `mode`: ignore-parent-results (subtest parents always pass), exclude-parents (subtest parents are excluded from the report)")
+	deterministic = flag.Bool("deterministic", false, "use hostname \"hostname\" and timestamp 2022-01-01T00:00:00Z in the report, so that the same input always results in the same report")
 
 	// debug flags
 	printEvents = flag.Bool("debug.print-events", false, "print events generated by the go test parser")
@@ -103,6 +104,7 @@ func main() {
 		SubtestMode:   subtestMode,
 		Properties:    properties,
 		PrintEvents:   *printEvents,
+		Deterministic: *deterministic,
 	}
 	report, err := config.Run(in, out)
 	if err != nil {
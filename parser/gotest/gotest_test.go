@@ -52,6 +52,14 @@ var parseLineTests = []parseLineTest{
 		"        --- FAIL: TestOne/Subtest/#01 (0.35s)",
 		Event{Type: "end_test", Name: "TestOne/Subtest/#01", Result: "FAIL", Duration: 350 * time.Millisecond, Indent: 2},
 	},
+	{
+		"--- FAIL: TestParens(a) (0.42s)",
+		Event{Type: "end_test", Name: "TestParens(a)", Result: "FAIL", Duration: 420 * time.Millisecond},
+	},
+	{
+		"    --- PASS: TestParens/(1.00s) (1.50s)",
+		Event{Type: "end_test", Name: "TestParens/(1.00s)", Result: "PASS", Duration: 1500 * time.Millisecond, Indent: 1},
+	},
 	{
 		"some text--- PASS: TestTwo (0.06 seconds)",
 		[]Event{
@@ -380,6 +388,44 @@ func TestReport(t *testing.T) {
 	}
 }
 
+func TestParseEndTestDurations(t *testing.T) {
+	input := `=== RUN   TestPass
+--- PASS: TestPass (0.42s)
+=== RUN   TestFail
+--- FAIL: TestFail (1.05s)
+=== RUN   TestSkip
+--- SKIP: TestSkip (0.10s)
+=== RUN   TestZero
+--- PASS: TestZero (0.00s)
+=== RUN   TestName(with)parens
+--- FAIL: TestName(with)parens (0.25s)
+FAIL
+FAIL	package/name	1.900s
+`
+	report, err := NewParser().Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Packages) != 1 {
+		t.Fatalf("unexpected number of packages, got %d, want 1", len(report.Packages))
+	}
+
+	got := make(map[string]time.Duration)
+	for _, test := range report.Packages[0].Tests {
+		got[test.Name] = test.Duration
+	}
+	want := map[string]time.Duration{
+		"TestPass":             420 * time.Millisecond,
+		"TestFail":             1050 * time.Millisecond,
+		"TestSkip":             100 * time.Millisecond,
+		"TestZero":             0,
+		"TestName(with)parens": 250 * time.Millisecond,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected test durations, diff (-want, +got):\n%v", diff)
+	}
+}
+
 func TestSubtestModes(t *testing.T) {
 	events := []Event{
 		{Type: "run_test", Name: "TestParent"},
@@ -9,7 +9,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/jstemmer/go-junit-report/v2/internal/gojunitreport"
 )
@@ -100,10 +99,7 @@ func createReportFromInput(inputFile, outputFile string, write bool) error {
 		config.Parser = "gojson"
 	}
 
-	config.Hostname = "hostname"
-	config.TimestampFunc = func() time.Time {
-		return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
-	}
+	config.Deterministic = true
 	config.Properties = map[string]string{"go.version": "1.0"}
 
 	_, err = config.Run(in, out)